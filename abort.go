@@ -0,0 +1,170 @@
+package quincy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// AbortError is passed to the OnError hook when a middleware explicitly aborts
+// the chain, with Abort or by returning an error from an ErrMiddleware. This
+// allows an explicit abort to be told apart from the request context being
+// cancelled, which is reported as context.Canceled when the client goes away or
+// context.DeadlineExceeded when a deadline expires. A chain ended with Stop is
+// not reported at all.
+//	var abortErr *quincy.AbortError
+//	if errors.As(err, &abortErr) {
+//		// a middleware rejected the request
+//	}
+type AbortError struct {
+	// Status is the status code written by Abort, or zero if none was written
+	Status int
+
+	// Err is the error returned by an ErrMiddleware
+	Err error
+}
+
+func (e *AbortError) Error() string {
+	if e.Err != nil {
+		return "quincy: chain aborted: " + e.Err.Error()
+	}
+	if e.Status != 0 {
+		return fmt.Sprintf("quincy: chain aborted with status %d", e.Status)
+	}
+	return "quincy: chain aborted"
+}
+
+// Unwrap returns the error returned by an ErrMiddleware
+func (e *AbortError) Unwrap() error {
+	return e.Err
+}
+
+// Abort writes the status code and returns a context that stops the chain, so
+// none of the remaining middleware or the final handler are called
+//	if !authorized {
+//		return quincy.Abort(c, w, http.StatusForbidden)
+//	}
+func Abort(c context.Context, w http.ResponseWriter, status int) context.Context {
+	w.WriteHeader(status)
+	return abortWith(c, &AbortError{Status: status})
+}
+
+// Fail returns a context that stops the chain like Abort, but leaves writing the
+// response to the OnError hook, which is given an AbortError holding the status
+// and error. If the hook writes nothing the status is written on its own.
+//	if err != nil {
+//		return quincy.Fail(c, http.StatusBadGateway, err)
+//	}
+func Fail(c context.Context, status int, err error) context.Context {
+	return abortWith(c, &AbortError{Status: status, Err: err})
+}
+
+// StatusClientClosedRequest is the nonstandard status returned by StatusOf when
+// the client went away before the response was written. Then and Handle never
+// write it, since there is no one left to receive it.
+const StatusClientClosedRequest = 499
+
+// StatusOf returns the status of the AbortError within the error chain, allowing
+// an OnError hook to respond with the status given to Fail. Without one, an
+// expired deadline gives a 504, a cancelled request gives
+// StatusClientClosedRequest and any other error a 500.
+func StatusOf(err error) int {
+	var abortErr *AbortError
+	if errors.As(err, &abortErr) && abortErr.Status != 0 {
+		return abortErr.Status
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// TimeoutStatus returns the status written for a request whose deadline expired,
+// which is the TimeoutStatus of the chain being run, or 504 if it is not set
+//	w.WriteHeader(quincy.TimeoutStatus(c))
+func TimeoutStatus(c context.Context) int {
+	if st := stateFrom(c); st != nil && st.timeoutStatus != 0 {
+		return st.timeoutStatus
+	}
+	return http.StatusGatewayTimeout
+}
+
+// returns the status of the error like StatusOf, using the TimeoutStatus of the
+// chain being run for an expired deadline
+func statusOf(c context.Context, err error) int {
+	status := StatusOf(err)
+	if status == http.StatusGatewayTimeout {
+		var abortErr *AbortError
+		if !errors.As(err, &abortErr) || abortErr.Status == 0 {
+			return TimeoutStatus(c)
+		}
+	}
+	return status
+}
+
+type stopKey struct{}
+
+// Stop returns a context that ends the chain without it being treated as an error,
+// for middleware that have already written a complete response, such as a
+// redirect. The remaining middleware and the final handler are skipped and the
+// OnError hook is not called.
+//	http.Redirect(w, r, "/login", http.StatusFound)
+//	return quincy.Stop(c)
+func Stop(c context.Context) context.Context {
+	return context.WithValue(c, stopKey{}, true)
+}
+
+// Terminal returns a middleware that responds to the request with fn and then
+// ends the chain with Stop, so the remaining middleware and the final handler are
+// skipped. Combined with When it responds early to some requests while letting
+// the others continue down the chain.
+//	q.Add(quincy.When(isMocked, quincy.Terminal(mockResponse)))
+func Terminal(fn HandlerFunc) Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		fn(c, w, r)
+		return Stop(c)
+	}
+}
+
+// reports whether the context was returned by Stop
+func stopped(c context.Context) bool {
+	return c.Value(stopKey{}) != nil
+}
+
+// ErrMiddleware is a Middleware that reports failure by returning an error
+type ErrMiddleware func(context.Context, http.ResponseWriter, *http.Request) (context.Context, error)
+
+// Wrap converts an ErrMiddleware into a Middleware. A non-nil error aborts the
+// chain and is passed on to the OnError hook.
+//	q := quincy.New(quincy.Wrap(func(c context.Context, w http.ResponseWriter, r *http.Request) (context.Context, error) {
+//		account, err := loadAccount(c, r)
+//		if err != nil {
+//			return c, err
+//		}
+//		return context.WithValue(c, "account", account), nil
+//	}))
+func Wrap(fn ErrMiddleware) Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		c, err := fn(c, w, r)
+		if err != nil {
+			return abortWith(c, &AbortError{Err: err})
+		}
+		return c
+	}
+}
+
+// returns a cancelled context to stop the chain
+func abort(c context.Context) context.Context {
+	return abortWith(c, &AbortError{})
+}
+
+// returns a context to stop the chain that is cancelled with the error as its cause
+func abortWith(c context.Context, err error) context.Context {
+	c, cancel := context.WithCancelCause(c)
+	cancel(err)
+	return c
+}
@@ -0,0 +1,268 @@
+package quincy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Abort(t *testing.T) {
+	var calls []string
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return Abort(c, w, http.StatusForbidden)
+	}
+
+	q := New(mw, record("next", &calls))
+	q.ContextFunc = background
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called")
+	})
+
+	w := httptest.NewRecorder()
+	fn(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusForbidden {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if len(calls) != 0 {
+		t.Error("middleware after the abort should not be called")
+	}
+}
+
+func Test_Wrap(t *testing.T) {
+	errFoo := errors.New("foo")
+	mw := Wrap(func(c context.Context, w http.ResponseWriter, r *http.Request) (context.Context, error) {
+		return c, errFoo
+	})
+
+	var calls []string
+	q := New(mw, record("next", &calls))
+	q.ContextFunc = background
+
+	var hookErr error
+	q.OnError(func(c context.Context, w http.ResponseWriter, r *http.Request, err error) {
+		hookErr = err
+	})
+
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called")
+	})
+	fn(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if len(calls) != 0 {
+		t.Error("middleware after the error should not be called")
+	}
+	if !errors.Is(hookErr, errFoo) {
+		t.Error("error hook did not receive the original error: ", hookErr)
+	}
+}
+
+func Test_Stop(t *testing.T) {
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return Stop(c)
+	}
+
+	var calls []string
+	q := New(mw, record("next", &calls))
+	q.ContextFunc = background
+	q.OnError(func(c context.Context, w http.ResponseWriter, r *http.Request, err error) {
+		t.Error("error hook should not be called")
+	})
+
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called")
+	})
+
+	w := httptest.NewRecorder()
+	fn(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusFound {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if len(calls) != 0 {
+		t.Error("middleware after the stop should not be called")
+	}
+}
+
+func Test_AbortReason(t *testing.T) {
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return Abort(c, w, http.StatusForbidden)
+	}
+
+	q := New(mw)
+	q.ContextFunc = background
+
+	var hookErr error
+	q.OnError(func(c context.Context, w http.ResponseWriter, r *http.Request, err error) {
+		hookErr = err
+	})
+	q.Then(nil)(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	var abortErr *AbortError
+	if !errors.As(hookErr, &abortErr) {
+		t.Error("expected an abort error: ", hookErr)
+		return
+	}
+	if abortErr.Status != http.StatusForbidden {
+		t.Error("invalid abort status: ", abortErr.Status)
+	}
+}
+
+func Test_CancelReason(t *testing.T) {
+	q := New(testMiddleware)
+	q.ContextFunc = func(r *http.Request) context.Context {
+		c, cancel := context.WithCancel(context.Background())
+		cancel()
+		return c
+	}
+
+	var hookErr error
+	q.OnError(func(c context.Context, w http.ResponseWriter, r *http.Request, err error) {
+		hookErr = err
+	})
+	q.Then(nil)(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	var abortErr *AbortError
+	if errors.As(hookErr, &abortErr) {
+		t.Error("cancellation should not be reported as an abort")
+	}
+	if hookErr != context.Canceled {
+		t.Error("expected a cancellation error: ", hookErr)
+	}
+}
+
+func Test_AbortWithoutStatus(t *testing.T) {
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return abort(c)
+	}
+
+	q := New(mw)
+	q.ContextFunc = background
+
+	w := httptest.NewRecorder()
+	q.Then(nil)(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Error("Invalid response status: ", w.Code)
+	}
+}
+
+func Test_Fail(t *testing.T) {
+	failure := errors.New("upstream unavailable")
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return Fail(c, http.StatusBadGateway, failure)
+	}
+
+	var hookErr error
+	q := New(mw)
+	q.ContextFunc = background
+	q.OnError(func(c context.Context, w http.ResponseWriter, r *http.Request, err error) {
+		hookErr = err
+	})
+
+	w := httptest.NewRecorder()
+	q.Then(nil)(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusBadGateway {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if !errors.Is(hookErr, failure) || StatusOf(hookErr) != http.StatusBadGateway {
+		t.Error("invalid hook error: ", hookErr)
+	}
+}
+
+func Test_DeadlineStatus(t *testing.T) {
+	expire := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		c, cancel := context.WithDeadline(c, time.Now())
+		Defer(c, cancel)
+		return c
+	}
+
+	tests := []struct {
+		timeoutStatus, expected int
+	}{
+		{0, http.StatusGatewayTimeout},
+		{http.StatusRequestTimeout, http.StatusRequestTimeout},
+	}
+	for _, test := range tests {
+		q := New(expire, testMiddleware)
+		q.ContextFunc = background
+		q.TimeoutStatus = test.timeoutStatus
+
+		w := httptest.NewRecorder()
+		q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+			t.Error("handler should not be called")
+		})(w, httptest.NewRequest("GET", "/", nil))
+
+		if w.Code != test.expected {
+			t.Errorf("expected %d, got %d", test.expected, w.Code)
+		}
+	}
+}
+
+func Test_ClientCancelStatus(t *testing.T) {
+	c, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := httptest.NewRequest("GET", "/", nil).WithContext(c)
+	r.Header.Set("Accept", "application/json")
+
+	var hookErr error
+	q := New(testMiddleware)
+	q.ContextFunc = func(r *http.Request) context.Context {
+		return r.Context()
+	}
+	q.OnError(JSONErrors(nil))
+	q.Finally(func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		hookErr = Err(c)
+		return c
+	})
+
+	rec := NewStatusRecorder(httptest.NewRecorder())
+	q.Then(nil)(rec, r)
+
+	if rec.Status() != 0 {
+		t.Error("nothing should be written for a cancelled request: ", rec.Status())
+	}
+	if hookErr != context.Canceled {
+		t.Error("invalid error: ", hookErr)
+	}
+	if StatusOf(hookErr) != StatusClientClosedRequest {
+		t.Error("invalid status: ", StatusOf(hookErr))
+	}
+}
+
+func Test_DeadlineStatusDerived(t *testing.T) {
+	expire := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		c, cancel := context.WithDeadline(c, time.Now())
+		Defer(c, cancel)
+		return c
+	}
+	handle := func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called")
+	}
+
+	q := New(expire)
+	q.ContextFunc = background
+	q.TimeoutStatus = http.StatusRequestTimeout
+
+	w := httptest.NewRecorder()
+	q.Group(testMiddleware).Then(handle)(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusRequestTimeout {
+		t.Error("Group should keep the timeout status: ", w.Code)
+	}
+
+	rt := NewRouter(expire)
+	rt.Chain().ContextFunc = background
+	rt.Chain().TimeoutStatus = http.StatusRequestTimeout
+	rt.Get("/", handle)
+
+	w = httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusRequestTimeout {
+		t.Error("routes should keep the timeout status: ", w.Code)
+	}
+}
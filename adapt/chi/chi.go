@@ -0,0 +1,42 @@
+package chi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/chrisolsen/quincy"
+	gochi "github.com/go-chi/chi/v5"
+)
+
+// URLParams returns a middleware that copies the URL params of the matched chi
+// route, which chi keeps on the request context, into the quincy Params, so
+// handlers read them with quincy.ParamsFrom. The pattern of the route is set as
+// the quincy route pattern. Requests not routed by chi are given empty Params.
+//	q := quincy.New(chi.URLParams())
+func URLParams() quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		p := quincy.Params{}
+		if rc := gochi.RouteContext(r.Context()); rc != nil {
+			for i, key := range rc.URLParams.Keys {
+				if i < len(rc.URLParams.Values) {
+					p[key] = rc.URLParams.Values[i]
+				}
+			}
+			if pattern := rc.RoutePattern(); pattern != "" {
+				c = quincy.WithRoutePattern(c, pattern)
+			}
+		}
+		return quincy.WithParams(c, p)
+	}
+}
+
+// Handler returns the chain, ending with fn, as a http.HandlerFunc that can be
+// registered on a chi router, with the URL params copied into the quincy Params
+// ahead of the chain. The chain is not modified.
+//	router := gochi.NewRouter()
+//	router.Get("/users/{id}", chi.Handler(q, showUser))
+func Handler(q *quincy.Q, fn quincy.HandlerFunc) http.HandlerFunc {
+	chain := q.Clone()
+	chain.Prepend(URLParams())
+	return chain.Then(fn)
+}
@@ -0,0 +1,41 @@
+package chi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/testutil"
+	gochi "github.com/go-chi/chi/v5"
+)
+
+func Test_Handler(t *testing.T) {
+	q := quincy.New()
+	q.ContextFunc = testutil.StubContext
+
+	var id, pattern string
+	router := gochi.NewRouter()
+	router.Get("/users/{id}", Handler(q, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		id = quincy.ParamsFrom(c).Get("id")
+		pattern = quincy.RoutePattern(c)
+	}))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	if id != "42" {
+		t.Error("invalid id param: ", id)
+	}
+	if pattern != "/users/{id}" {
+		t.Error("invalid route pattern: ", pattern)
+	}
+}
+
+func Test_URLParams_Unrouted(t *testing.T) {
+	c := URLParams()(context.Background(), nil, httptest.NewRequest("GET", "/", nil))
+
+	p := quincy.ParamsFrom(c)
+	if p == nil || len(p) != 0 {
+		t.Error("expected empty params: ", p)
+	}
+}
@@ -0,0 +1,35 @@
+package httprouter
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/chrisolsen/quincy"
+	julien "github.com/julienschmidt/httprouter"
+)
+
+type paramsKey struct{}
+
+// Handle returns the chain, ending with fn, as a julienschmidt/httprouter Handle.
+// The route params, which httprouter passes to the Handle rather than on the
+// request, are captured ahead of the chain and set as the quincy Params, so both
+// the middleware and fn read them with quincy.ParamsFrom. The chain is not
+// modified.
+//	router := julien.New()
+//	router.GET("/users/:id", httprouter.Handle(q, showUser))
+func Handle(q *quincy.Q, fn quincy.HandlerFunc) julien.Handle {
+	chain := q.Clone()
+	chain.Prepend(func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		ps, _ := r.Context().Value(paramsKey{}).(julien.Params)
+		p := make(quincy.Params, len(ps))
+		for _, param := range ps {
+			p[param.Key] = param.Value
+		}
+		return quincy.WithParams(c, p)
+	})
+	serve := chain.Then(fn)
+
+	return func(w http.ResponseWriter, r *http.Request, ps julien.Params) {
+		serve(w, r.WithContext(context.WithValue(r.Context(), paramsKey{}, ps)))
+	}
+}
@@ -0,0 +1,34 @@
+package httprouter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/testutil"
+	julien "github.com/julienschmidt/httprouter"
+)
+
+func Test_Handle(t *testing.T) {
+	var mwID, id string
+	q := quincy.New(func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		mwID = quincy.ParamsFrom(c).Get("id")
+		return c
+	})
+	q.ContextFunc = testutil.StubContext
+
+	router := julien.New()
+	router.GET("/users/:id", Handle(q, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		id = quincy.ParamsFrom(c).Get("id")
+	}))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	if id != "42" || mwID != "42" {
+		t.Error("invalid id param: ", id, mwID)
+	}
+	if q.Len() != 1 {
+		t.Error("chain should not be modified")
+	}
+}
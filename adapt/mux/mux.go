@@ -0,0 +1,32 @@
+package mux
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/chrisolsen/quincy"
+	gorilla "github.com/gorilla/mux"
+)
+
+// Vars returns a middleware that copies the variables of the matched gorilla/mux
+// route into the quincy Params, so handlers read them with quincy.ParamsFrom. The
+// path template of the route is set as the quincy route pattern. Requests not
+// routed by gorilla/mux are given empty Params.
+//	router := gorilla.NewRouter()
+//	q := quincy.New(mux.Vars())
+//	router.HandleFunc("/users/{id}", q.Then(showUser))
+func Vars() quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		vars := gorilla.Vars(r)
+		p := make(quincy.Params, len(vars))
+		for k, v := range vars {
+			p[k] = v
+		}
+		if route := gorilla.CurrentRoute(r); route != nil {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				c = quincy.WithRoutePattern(c, tpl)
+			}
+		}
+		return quincy.WithParams(c, p)
+	}
+}
@@ -1,12 +1,13 @@
 package basicauth
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/base64"
 	"net/http"
 	"strings"
 
 	"github.com/chrisolsen/quincy"
-	"golang.org/x/net/context"
 )
 
 // Auth is the function type of the custom function that is required to perform the custom authentication
@@ -17,10 +18,7 @@ func Authenticate(auth Auth) quincy.Middleware {
 	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
 		reject := func() context.Context {
 			w.Header().Set("WWW-Authenticate", `Basic realm=""`)
-			w.WriteHeader(http.StatusUnauthorized)
-			c, cancel := context.WithCancel(c)
-			defer cancel()
-			return c
+			return quincy.Abort(c, w, http.StatusUnauthorized)
 		}
 
 		authHeader := r.Header.Get("Authorization")
@@ -47,3 +45,46 @@ func Authenticate(auth Auth) quincy.Middleware {
 		return c
 	}
 }
+
+type user string
+
+// Check returns a middleware that authenticates the request credentials with the
+// check function, prompting for credentials within the realm when they are
+// missing or rejected. On success the user name is stored on the context.
+//	q := quincy.New(basicauth.Check("admin", basicauth.Static("admin", secret)))
+func Check(realm string, check func(name, password string) bool) quincy.Middleware {
+	challenge := `Basic realm="` + strings.ReplaceAll(realm, `"`, `\"`) + `"`
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		name, password, ok := r.BasicAuth()
+		if !ok || !check(name, password) {
+			w.Header().Set("WWW-Authenticate", challenge)
+			return quincy.Abort(c, w, http.StatusUnauthorized)
+		}
+		return quincy.WithValue(c, user(name))
+	}
+}
+
+// User returns the name of the user authenticated by Check, or an empty string if
+// the request was not authenticated
+func User(c context.Context) string {
+	name, _ := quincy.Value[user](c)
+	return string(name)
+}
+
+// Static returns a check function that accepts the single name and password
+// provided. Both are compared in constant time so the time taken does not reveal
+// how much of the credentials were correct.
+func Static(name, password string) func(string, string) bool {
+	return func(n, p string) bool {
+		validName := Compare(n, name)
+		validPassword := Compare(p, password)
+		return validName && validPassword
+	}
+}
+
+// Compare reports whether the given and expected values match, in time that does
+// not depend on how many of the leading bytes match
+func Compare(given, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(given), []byte(expected)) == 1
+}
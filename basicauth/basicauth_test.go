@@ -1,13 +1,13 @@
 package basicauth
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/chrisolsen/quincy"
-	"golang.org/x/net/context"
 	"google.golang.org/appengine"
 	"google.golang.org/appengine/aetest"
 )
@@ -106,3 +106,54 @@ func Test_StoreAuthTokenInContext(t *testing.T) {
 	q := quincy.New(mw1, mw2)
 	q.Run(c, w, r)
 }
+
+func Test_CheckMissingHeader(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	c := Check("admin", Static("foo", "bar"))(context.Background(), w, r)
+
+	if c.Err() == nil {
+		t.Error("chain should be aborted")
+	}
+	if w.Code != http.StatusUnauthorized {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if w.Header().Get("WWW-Authenticate") != `Basic realm="admin"` {
+		t.Error("invalid www-authenticate header: ", w.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func Test_CheckInvalidCredentials(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.SetBasicAuth("foo", "baz")
+	w := httptest.NewRecorder()
+
+	c := Check("admin", Static("foo", "bar"))(context.Background(), w, r)
+
+	if c.Err() == nil {
+		t.Error("chain should be aborted")
+	}
+	if w.Code != http.StatusUnauthorized {
+		t.Error("Invalid response status: ", w.Code)
+	}
+}
+
+func Test_CheckValidCredentials(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.SetBasicAuth("foo", "bar")
+	w := httptest.NewRecorder()
+
+	var name string
+	q := quincy.New(Check("admin", Static("foo", "bar")), func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		name = User(c)
+		return c
+	})
+
+	if err := q.RunE(context.Background(), w, r); err != nil {
+		t.Error("chain should not be aborted: ", err)
+	}
+	if name != "foo" {
+		t.Error("invalid user name: ", name)
+	}
+}
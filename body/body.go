@@ -0,0 +1,74 @@
+package body
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// the most bytes of an unread body that are drained before it is closed, past
+// which the connection is better closed than kept alive
+const maxDrain = 256 << 10
+
+// Limit returns a middleware that caps the size of the request body at n bytes.
+// Requests declaring a larger Content-Length are rejected with a 413 before the
+// body is read, while bodies of an unknown length, such as chunked requests, fail
+// with an error once more than n bytes are read.
+//	q := quincy.New(body.Limit(1 << 20))
+func Limit(n int64) quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		if r.ContentLength > n {
+			return quincy.Abort(c, w, http.StatusRequestEntityTooLarge)
+		}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+		}
+		return c
+	}
+}
+
+// RequireLength returns a middleware that checks the Content-Length of POST, PUT
+// and PATCH requests, aborting the chain with a 411 when it is missing, such as
+// for chunked requests, a 400 when it is below min, or a 413 when it is above max.
+// A max of zero allows any length. Unlike Limit it only checks the declared
+// length, so the two can be combined.
+//	q := quincy.New(body.RequireLength(1, 1<<20))
+func RequireLength(min, max int64) quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		switch r.Method {
+		case "POST", "PUT", "PATCH":
+		default:
+			return c
+		}
+
+		switch n := r.ContentLength; {
+		case n < 0 || (n == 0 && r.Header.Get("Content-Length") == ""):
+			return quincy.Abort(c, w, http.StatusLengthRequired)
+		case n < min:
+			return quincy.Abort(c, w, http.StatusBadRequest)
+		case max > 0 && n > max:
+			return quincy.Abort(c, w, http.StatusRequestEntityTooLarge)
+		}
+		return c
+	}
+}
+
+// Close returns a middleware that drains and closes the request body once the
+// request completes, whether or not the handler read it, allowing the connection
+// to be reused. At most 256KB are drained, leaving larger bodies to be discarded
+// along with the connection.
+//	q := quincy.New(body.Close())
+func Close() quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		if r.Body == nil || r.Body == http.NoBody {
+			return c
+		}
+		quincy.Defer(c, func() {
+			io.CopyN(io.Discard, r.Body, maxDrain)
+			r.Body.Close()
+		})
+		return c
+	}
+}
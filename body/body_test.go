@@ -0,0 +1,154 @@
+package body
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/testutil"
+)
+
+func serve(mw quincy.Middleware, r *http.Request, fn quincy.HandlerFunc) *httptest.ResponseRecorder {
+	q := quincy.New(mw)
+	q.ContextFunc = testutil.StubContext
+
+	w := httptest.NewRecorder()
+	q.Then(fn)(w, r)
+	return w
+}
+
+func Test_Limit(t *testing.T) {
+	var read string
+	r := httptest.NewRequest("POST", "/", strings.NewReader("small"))
+	serve(Limit(10), r, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error("unexpected error: ", err)
+		}
+		read = string(b)
+	})
+
+	if read != "small" {
+		t.Error("body not passed to the handler: ", read)
+	}
+}
+
+func Test_Limit_ContentLength(t *testing.T) {
+	called := false
+	r := httptest.NewRequest("POST", "/", strings.NewReader("this is too large"))
+	w := serve(Limit(10), r, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	if w.Code != 413 {
+		t.Error("expected 413, got ", w.Code)
+	}
+	if called {
+		t.Error("handler should not be called")
+	}
+}
+
+func Test_Limit_Chunked(t *testing.T) {
+	var err error
+	r := httptest.NewRequest("POST", "/", strings.NewReader("this is too large"))
+	r.ContentLength = -1
+	serve(Limit(10), r, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		_, err = io.ReadAll(r.Body)
+	})
+
+	var maxErr *http.MaxBytesError
+	if !errors.As(err, &maxErr) {
+		t.Error("expected a MaxBytesError, got ", err)
+	}
+}
+
+func Test_RequireLength(t *testing.T) {
+	tests := []struct {
+		method   string
+		length   int64
+		header   bool
+		expected int
+	}{
+		{"POST", 5, true, 200},
+		{"POST", -1, false, 411},
+		{"POST", 0, false, 411},
+		{"POST", 0, true, 400},
+		{"POST", 1, true, 400},
+		{"PUT", 11, true, 413},
+		{"PATCH", 10, true, 200},
+		{"GET", -1, false, 200},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, "/", strings.NewReader(strings.Repeat("a", max(int(test.length), 0))))
+		r.ContentLength = test.length
+		if test.header {
+			r.Header.Set("Content-Length", strconv.FormatInt(test.length, 10))
+		}
+		w := httptest.NewRecorder()
+		RequireLength(2, 10)(context.Background(), w, r)
+
+		if w.Code != test.expected {
+			t.Errorf("%s %d: expected %d, got %d", test.method, test.length, test.expected, w.Code)
+		}
+	}
+}
+
+type trackedBody struct {
+	io.Reader
+	read   int64
+	closed bool
+}
+
+func (b *trackedBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	b.read += int64(n)
+	return n, err
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func Test_Close(t *testing.T) {
+	small := &trackedBody{Reader: strings.NewReader("unread")}
+	huge := &trackedBody{Reader: io.LimitReader(zeros{}, 10<<20)}
+
+	for _, b := range []*trackedBody{small, huge} {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Body = b
+		serve(Close(), r, func(c context.Context, w http.ResponseWriter, r *http.Request) {})
+
+		if !b.closed {
+			t.Error("body not closed")
+		}
+	}
+
+	if small.read != 6 {
+		t.Error("small body not drained: ", small.read)
+	}
+	if huge.read > maxDrain {
+		t.Error("huge body should not be fully drained: ", huge.read)
+	}
+}
+
+func Test_Close_NoBody(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Body = nil
+	// should not panic
+	serve(Close(), r, func(c context.Context, w http.ResponseWriter, r *http.Request) {})
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+	"google.golang.org/appengine/memcache"
+)
+
+// the largest response that is cached, leaving room within the 1MB memcache item
+// limit for the key and encoding
+const maxSize = 1000 << 10
+
+// Store keeps the cached responses
+type Store interface {
+	// Get returns the value of the key, with ok being false on a miss
+	Get(c context.Context, key string) (value []byte, ok bool, err error)
+
+	// Set stores the value under the key for the ttl provided
+	Set(c context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+type config struct {
+	namespace string
+	vary      []string
+	bypass    func(*http.Request) bool
+	store     Store
+}
+
+// Option configures the cache middleware
+type Option func(*config)
+
+// Namespace sets the prefix of the cache keys, which defaults to "quincy-cache"
+func Namespace(ns string) Option {
+	return func(cfg *config) {
+		cfg.namespace = ns
+	}
+}
+
+// Vary adds the values of the request headers to the cache key, so requests that
+// differ by them are cached separately
+func Vary(headers ...string) Option {
+	return func(cfg *config) {
+		cfg.vary = append(cfg.vary, headers...)
+	}
+}
+
+// Bypass sets the function reporting whether a request skips the cache, replacing
+// the default of skipping requests with an Authorization header or a cookie, since
+// their responses are likely to be personal
+func Bypass(fn func(*http.Request) bool) Option {
+	return func(cfg *config) {
+		cfg.bypass = fn
+	}
+}
+
+// WithStore keeps the responses in the store rather than in App Engine memcache
+func WithStore(s Store) Option {
+	return func(cfg *config) {
+		cfg.store = s
+	}
+}
+
+// entry is a cached response
+type entry struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Response returns a middleware that caches the responses of GET requests for
+// the ttl, serving a cached response in place of running the rest of the chain.
+// Only 200 responses are cached, and not when the handler sets a Cache-Control
+// of no-store or private, or sets a cookie. Only the headers added by the rest of
+// the chain are cached, and a cached header never replaces one already set for
+// the request, so headers such as X-Request-ID or those of CORS are not served to
+// other clients. A failure to reach the cache is treated as a miss.
+//	q := quincy.New(cache.Response(5*time.Minute, cache.Vary("Accept-Language")))
+func Response(ttl time.Duration, opts ...Option) quincy.Middleware {
+	cfg := config{
+		namespace: "quincy-cache",
+		bypass:    personal,
+		store:     MemcacheStore{},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		if r.Method != "GET" || cfg.bypass(r) {
+			return c
+		}
+
+		key := cfg.key(r)
+		if b, ok, err := cfg.store.Get(c, key); err == nil && ok {
+			var e entry
+			if gob.NewDecoder(bytes.NewReader(b)).Decode(&e) == nil {
+				h := w.Header()
+				for k, v := range e.Header {
+					if _, ok := h[k]; !ok {
+						h[k] = v
+					}
+				}
+				w.WriteHeader(e.Status)
+				w.Write(e.Body)
+				return quincy.Stop(c)
+			}
+		}
+
+		cw := &captureWriter{Wrapper: quincy.Wrapper{ResponseWriter: w}, before: w.Header().Clone()}
+		quincy.Defer(c, func() {
+			if e, ok := cw.entry(); ok {
+				var buf bytes.Buffer
+				if gob.NewEncoder(&buf).Encode(e) == nil {
+					cfg.store.Set(c, key, buf.Bytes(), ttl)
+				}
+			}
+		})
+		return quincy.WithWriter(c, cw)
+	}
+}
+
+// returns the cache key of the request
+func (cfg *config) key(r *http.Request) string {
+	h := sha1.New()
+	h.Write([]byte(r.Host + r.URL.RequestURI()))
+	for _, name := range cfg.vary {
+		h.Write([]byte{0})
+		h.Write([]byte(r.Header.Get(name)))
+	}
+	return cfg.namespace + ":" + hex.EncodeToString(h.Sum(nil))
+}
+
+// reports whether the request carries credentials
+func personal(r *http.Request) bool {
+	return r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != ""
+}
+
+// captureWriter records the response while writing it on to the client
+type captureWriter struct {
+	quincy.Wrapper
+	status      int
+	before      http.Header
+	header      http.Header
+	body        []byte
+	tooLarge    bool
+	uncacheable bool
+}
+
+func (cw *captureWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+		h := cw.Header()
+		cc := strings.ToLower(h.Get("Cache-Control"))
+		cw.uncacheable = strings.Contains(cc, "no-store") || strings.Contains(cc, "private") || h.Get("Set-Cookie") != ""
+		cw.header = added(h, cw.before)
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+// returns the headers of h that were added or changed since the snapshot
+func added(h, snapshot http.Header) http.Header {
+	diff := http.Header{}
+	for k, v := range h {
+		if old, ok := snapshot[k]; !ok || !slices.Equal(old, v) {
+			diff[k] = append([]string(nil), v...)
+		}
+	}
+	return diff
+}
+
+func (cw *captureWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.tooLarge {
+		if len(cw.body)+len(b) > maxSize {
+			cw.tooLarge = true
+			cw.body = nil
+		} else {
+			cw.body = append(cw.body, b...)
+		}
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// returns the captured response, with ok being false if it can't be cached
+func (cw *captureWriter) entry() (entry, bool) {
+	if cw.status != http.StatusOK || cw.tooLarge || cw.uncacheable {
+		return entry{}, false
+	}
+	return entry{Status: cw.status, Header: cw.header, Body: cw.body}, true
+}
+
+// MemcacheStore keeps the cached responses within App Engine memcache
+type MemcacheStore struct{}
+
+// Get returns the value held in memcache
+func (MemcacheStore) Get(c context.Context, key string) ([]byte, bool, error) {
+	item, err := memcache.Get(c, key)
+	if err == memcache.ErrCacheMiss {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return item.Value, true, nil
+}
+
+// Set stores the value within memcache
+func (MemcacheStore) Set(c context.Context, key string, value []byte, ttl time.Duration) error {
+	return memcache.Set(c, &memcache.Item{Key: key, Value: value, Expiration: ttl})
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/testutil"
+	"github.com/chrisolsen/quincy/cors"
+	"github.com/chrisolsen/quincy/requestid"
+)
+
+type memStore map[string][]byte
+
+func (m memStore) Get(c context.Context, key string) ([]byte, bool, error) {
+	b, ok := m[key]
+	return b, ok, nil
+}
+
+func (m memStore) Set(c context.Context, key string, value []byte, ttl time.Duration) error {
+	m[key] = value
+	return nil
+}
+
+func handler(store memStore, calls *int, fn quincy.HandlerFunc, opts ...Option) func(http.ResponseWriter, *http.Request) {
+	q := quincy.New(Response(time.Minute, append(opts, WithStore(store))...))
+	q.ContextFunc = testutil.StubContext
+	return q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		*calls++
+		fn(c, w, r)
+	})
+}
+
+func hello(c context.Context, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte("hello " + r.Header.Get("Accept-Language")))
+}
+
+func Test_Response(t *testing.T) {
+	store, calls := memStore{}, 0
+	h := handler(store, &calls, hello)
+
+	cold := httptest.NewRecorder()
+	h(cold, httptest.NewRequest("GET", "/greeting", nil))
+	warm := httptest.NewRecorder()
+	h(warm, httptest.NewRequest("GET", "/greeting", nil))
+
+	if calls != 1 {
+		t.Error("handler should only be called on the miss, got ", calls)
+	}
+	if len(store) != 1 {
+		t.Error("response not cached")
+	}
+	if warm.Code != 200 || warm.Body.String() != "hello " || warm.Header().Get("Content-Type") != "text/plain" {
+		t.Error("invalid cached response: ", warm.Code, warm.Body.String(), warm.Header())
+	}
+}
+
+func Test_Response_Vary(t *testing.T) {
+	store, calls := memStore{}, 0
+	h := handler(store, &calls, hello, Vary("Accept-Language"))
+
+	for _, lang := range []string{"en", "fr", "en"} {
+		r := httptest.NewRequest("GET", "/greeting", nil)
+		r.Header.Set("Accept-Language", lang)
+		w := httptest.NewRecorder()
+		h(w, r)
+
+		if w.Body.String() != "hello "+lang {
+			t.Error("invalid response: ", w.Body.String())
+		}
+	}
+	if calls != 2 {
+		t.Error("expected a miss for each language, got ", calls)
+	}
+}
+
+func Test_Response_NotCached(t *testing.T) {
+	tests := map[string]quincy.HandlerFunc{
+		"not found": func(c context.Context, w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		},
+		"no-store": func(c context.Context, w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-store")
+			w.Write([]byte("secret"))
+		},
+		"cookie": func(c context.Context, w http.ResponseWriter, r *http.Request) {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+			w.Write([]byte("hi"))
+		},
+	}
+
+	for name, fn := range tests {
+		store, calls := memStore{}, 0
+		handler(store, &calls, fn)(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		if len(store) != 0 {
+			t.Errorf("%s: response should not be cached", name)
+		}
+	}
+}
+
+func Test_Response_Bypass(t *testing.T) {
+	store, calls := memStore{}, 0
+	h := handler(store, &calls, hello)
+
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", "Bearer token")
+		h(httptest.NewRecorder(), r)
+	}
+	h(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil))
+
+	if calls != 3 || len(store) != 0 {
+		t.Error("authenticated and non GET requests should bypass the cache")
+	}
+}
+
+func Test_Response_EarlierHeaders(t *testing.T) {
+	store := memStore{}
+	q := quincy.New(
+		requestid.Inject(),
+		cors.Allow(cors.Config{Origins: []string{"https://a.com", "https://b.com"}}),
+		Response(time.Minute, WithStore(store)),
+	)
+	q.ContextFunc = testutil.StubContext
+	h := q.Then(hello)
+
+	serve := func(origin string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/greeting", nil)
+		r.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w
+	}
+	cold := serve("https://a.com")
+	warm := serve("https://b.com")
+
+	if len(store) != 1 {
+		t.Error("response not cached")
+	}
+	if warm.Body.String() != cold.Body.String() || warm.Header().Get("Content-Type") != "text/plain" {
+		t.Error("invalid cached response: ", warm.Body.String(), warm.Header())
+	}
+	if id := warm.Header().Get(requestid.Header); id == "" || id == cold.Header().Get(requestid.Header) {
+		t.Error("cached response should keep its own request ID: ", id)
+	}
+	if origin := warm.Header().Get("Access-Control-Allow-Origin"); origin != "https://b.com" {
+		t.Error("cached response should keep its own allowed origin: ", origin)
+	}
+	if vary := warm.Header().Values("Vary"); len(vary) != 1 {
+		t.Error("cached Vary should not be added to the fresh one: ", vary)
+	}
+}
@@ -0,0 +1,35 @@
+package quincy
+
+import (
+	"context"
+	"net/http"
+)
+
+// Chain is the compiled composition of a chain's middleware. It can be run
+// directly, added to another chain as a single middleware with Middleware, or
+// used as the final Handler of another chain.
+type Chain Middleware
+
+// Compile returns the composed middleware of the chain. The Chain holds a
+// snapshot of the middleware, so changes made to q afterwards don't affect it.
+//	auth := quincy.New(session, user).Compile()
+//	api := quincy.New(cors, auth.Middleware())
+func (q *Q) Compile() Chain {
+	return Chain(q.chain())
+}
+
+// Run executes the compiled middleware in order, returning the resulting context
+func (ch Chain) Run(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+	return ch(c, w, r)
+}
+
+// ServeHTTP executes the compiled middleware, allowing the Chain to be used as a
+// Handler
+func (ch Chain) ServeHTTP(c context.Context, w http.ResponseWriter, r *http.Request) {
+	ch(c, w, r)
+}
+
+// Middleware returns the Chain as a single middleware
+func (ch Chain) Middleware() Middleware {
+	return Middleware(ch)
+}
@@ -0,0 +1,40 @@
+package quincy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_Compile(t *testing.T) {
+	var calls []string
+	q := New(record("a", &calls), record("b", &calls))
+	ch := q.Compile()
+	q.Add(record("c", &calls))
+
+	ch.Run(context.Background(), httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if strings.Join(calls, ",") != "a,b" {
+		t.Error("compiled chain should not include later middleware: ", calls)
+	}
+}
+
+func Test_CompileNested(t *testing.T) {
+	var calls []string
+	inner := New(record("b", &calls), record("c", &calls)).Compile()
+	q := New(record("a", &calls), inner.Middleware())
+	q.ContextFunc = background
+
+	q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	})(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	outer := New(record("d", &calls))
+	outer.ContextFunc = background
+	outer.Handle(inner).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if strings.Join(calls, ",") != "a,b,c,handler,d,b,c" {
+		t.Error("invalid calls: ", calls)
+	}
+}
@@ -0,0 +1,194 @@
+package compress
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// the size below which responses are not compressed by default
+const defaultMinSize = 1024
+
+type config struct {
+	minSize int
+}
+
+// Option configures the compression middleware
+type Option func(*config)
+
+// MinSize sets the number of bytes a response must reach before it is compressed,
+// since compressing tiny responses costs more than it saves
+func MinSize(n int) Option {
+	return func(cfg *config) {
+		cfg.minSize = n
+	}
+}
+
+// Gzip returns a middleware that compresses the response for clients that accept
+// gzip encoding. The ResponseWriter is replaced for the rest of the chain and the
+// compressed stream is closed once the request completes. Responses that are
+// smaller than the minimum size, or that have their own Content-Encoding, are
+// written as is. The stream can only be closed once the request completes with
+// Then and Handle, so chains run with Run are left uncompressed.
+//	q := quincy.New(compress.Gzip(compress.MinSize(512)))
+func Gzip(opts ...Option) quincy.Middleware {
+	cfg := config{minSize: defaultMinSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) {
+			return c
+		}
+
+		gw := &gzipWriter{Wrapper: quincy.Wrapper{ResponseWriter: w}, c: c, minSize: cfg.minSize}
+		if !quincy.Defer(c, gw.Close) {
+			return c
+		}
+		return quincy.WithWriter(c, gw)
+	}
+}
+
+// reports whether the Accept-Encoding header includes gzip without a zero quality
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			continue
+		}
+		_, q, found := strings.Cut(strings.ReplaceAll(params, " ", ""), "q=")
+		if !found {
+			return true
+		}
+		v, err := strconv.ParseFloat(q, 64)
+		return err == nil && v > 0
+	}
+	return false
+}
+
+// gzipWriter buffers the response until it reaches the minimum size, at which
+// point the rest of the response is compressed
+type gzipWriter struct {
+	quincy.Wrapper
+	c       context.Context
+	minSize int
+	status  int
+	buf     []byte
+	gz      *gzip.Writer
+	started bool
+}
+
+func (g *gzipWriter) WriteHeader(status int) {
+	if g.started || g.status != 0 {
+		return
+	}
+	g.status = status
+	// responses without a body can be sent on straight away
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		g.start(false)
+	}
+}
+
+func (g *gzipWriter) Write(b []byte) (int, error) {
+	if g.started {
+		if g.gz != nil {
+			return g.gz.Write(b)
+		}
+		return g.ResponseWriter.Write(b)
+	}
+
+	g.buf = append(g.buf, b...)
+	if len(g.buf) >= g.minSize {
+		if err := g.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush compresses and sends the response written so far, since a flushing handler
+// is streaming its response
+func (g *gzipWriter) Flush() {
+	if !g.started {
+		g.start(true)
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	g.Wrapper.Flush()
+}
+
+// Hijack takes over the connection if the underlying writer supports it, after
+// which nothing more is written through the gzip stream
+func (g *gzipWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := g.Wrapper.Hijack()
+	if err == nil {
+		g.started = true
+		g.gz = nil
+		g.buf = nil
+	}
+	return conn, rw, err
+}
+
+// Close writes out any buffered response and ends the compressed stream. The
+// buffered response is dropped if the request failed or a response was written
+// around the gzip writer, such as the 500 of a recovered panic, since it would
+// otherwise be appended to that response.
+func (g *gzipWriter) Close() {
+	if !g.started {
+		if quincy.Err(g.c) != nil || written(g.ResponseWriter) {
+			g.started = true
+			g.buf = nil
+			return
+		}
+		g.start(false)
+	}
+	if g.gz != nil {
+		g.gz.Close()
+	}
+}
+
+// writes the header and the buffered bytes, compressing the response from here on
+// if requested and the handler has not encoded the response itself
+func (g *gzipWriter) start(compress bool) error {
+	g.started = true
+	h := g.Header()
+	if compress && h.Get("Content-Encoding") == "" {
+		if h.Get("Content-Type") == "" && len(g.buf) > 0 {
+			h.Set("Content-Type", http.DetectContentType(g.buf))
+		}
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+	}
+
+	if g.status != 0 {
+		g.ResponseWriter.WriteHeader(g.status)
+	}
+	if len(g.buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if g.gz != nil {
+		_, err = g.gz.Write(g.buf)
+	} else {
+		_, err = g.ResponseWriter.Write(g.buf)
+	}
+	g.buf = nil
+	return err
+}
+
+// reports whether a status has been written to w
+func written(w http.ResponseWriter) bool {
+	rec, ok := w.(*quincy.StatusRecorder)
+	return ok && rec.Status() != 0
+}
@@ -0,0 +1,240 @@
+package compress
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/testutil"
+)
+
+var body = strings.Repeat("foobar ", 500)
+
+func serve(r *http.Request, opts ...Option) *httptest.ResponseRecorder {
+	q := quincy.New(Gzip(opts...))
+	q.ContextFunc = testutil.StubContext
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, body)
+	})
+
+	w := httptest.NewRecorder()
+	fn(w, r)
+	return w
+}
+
+func Test_Compressed(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "deflate, gzip")
+	w := serve(r)
+
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Error("response not compressed")
+		return
+	}
+	if w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Error("missing Vary header")
+	}
+
+	gr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Error("invalid gzip body: ", err)
+		return
+	}
+	b, _ := io.ReadAll(gr)
+	if string(b) != body {
+		t.Error("decompressed body does not match the expected")
+	}
+}
+
+func Test_NotAccepted(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip;q=0")
+	w := serve(r)
+
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Error("response should not be compressed")
+	}
+	if w.Body.String() != body {
+		t.Error("body does not match the expected")
+	}
+}
+
+func Test_BelowMinSize(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := serve(r, MinSize(len(body)+1))
+
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Error("response should not be compressed")
+	}
+	if w.Body.String() != body {
+		t.Error("body does not match the expected")
+	}
+}
+
+func Test_Flush(t *testing.T) {
+	q := quincy.New(Gzip())
+	q.ContextFunc = testutil.StubContext
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Error("writer should implement http.Flusher")
+			return
+		}
+		io.WriteString(w, "foo")
+		f.Flush()
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	fn(w, r)
+
+	if !w.Flushed {
+		t.Error("flush was not delegated to the underlying writer")
+	}
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Error("flushed response should be compressed")
+	}
+	gr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Error("invalid gzip body: ", err)
+		return
+	}
+	if b, _ := io.ReadAll(gr); string(b) != "foo" {
+		t.Error("invalid body: ", string(b))
+	}
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return h.conn, bufio.NewReadWriter(bufio.NewReader(h.conn), bufio.NewWriter(h.conn)), nil
+}
+
+func Test_Hijack(t *testing.T) {
+	q := quincy.New(Gzip())
+	q.ContextFunc = testutil.StubContext
+	server, client := net.Pipe()
+	defer client.Close()
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		h, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("writer should implement http.Hijacker")
+			return
+		}
+		if conn, _, err := h.Hijack(); err != nil || conn != server {
+			t.Error("hijack was not delegated to the underlying writer: ", err)
+		}
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := &hijackRecorder{httptest.NewRecorder(), server}
+	fn(w, r)
+
+	if w.Body.Len() != 0 || w.Header().Get("Content-Encoding") != "" {
+		t.Error("nothing should be written after the connection is hijacked")
+	}
+}
+
+func Test_PanicDropsBuffer(t *testing.T) {
+	q := quincy.New(quincy.Recover(), Gzip())
+	q.ContextFunc = testutil.StubContext
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "partial secret")
+		panic("failure")
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	fn(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Error("invalid response status: ", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "partial secret") {
+		t.Error("buffered body should not be written after a panic: ", w.Body.String())
+	}
+}
+
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+	deadline time.Time
+}
+
+func (d *deadlineRecorder) SetWriteDeadline(t time.Time) error {
+	d.deadline = t
+	return nil
+}
+
+func Test_ResponseController(t *testing.T) {
+	q := quincy.New(Gzip())
+	q.ContextFunc = testutil.StubContext
+	deadline := time.Now().Add(time.Minute)
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil {
+			t.Error("unexpected error: ", err)
+		}
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+	fn(w, r)
+
+	if !w.deadline.Equal(deadline) {
+		t.Error("the write deadline should reach the underlying writer: ", w.deadline)
+	}
+}
+
+// plainWriter is a ResponseWriter that doesn't support flushing
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+func Test_SSEWriter(t *testing.T) {
+	q := quincy.New(Gzip())
+	q.ContextFunc = testutil.StubContext
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		if err := quincy.NewSSEWriter(w).Send("update", "foo"); err != quincy.ErrNotFlusher {
+			t.Error("invalid error: ", err)
+		}
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	fn(plainWriter{httptest.NewRecorder()}, r)
+}
+
+func Test_Run(t *testing.T) {
+	q := quincy.New(Gzip(), func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		io.WriteString(w, body)
+		return c
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	q.Run(context.Background(), w, r)
+
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Error("response should not be compressed without Then")
+	}
+	if w.Body.String() != body {
+		t.Error("body written through Run was lost: ", w.Body.Len())
+	}
+}
@@ -0,0 +1,42 @@
+package content
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// Require returns a middleware that rejects POST, PUT and PATCH requests with a
+// body whose Content-Type is not one of the types provided, writing a 415. Any
+// parameters of the Content-Type, such as the charset, are ignored.
+//	q := quincy.New(content.Require("application/json"))
+func Require(types ...string) quincy.Middleware {
+	allowed := make(map[string]bool, len(types))
+	for _, t := range types {
+		allowed[strings.ToLower(t)] = true
+	}
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		if !hasBody(r) {
+			return c
+		}
+
+		t, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || !allowed[t] {
+			return quincy.Abort(c, w, http.StatusUnsupportedMediaType)
+		}
+		return c
+	}
+}
+
+// reports whether the request is of a method that carries a body and has one
+func hasBody(r *http.Request) bool {
+	switch r.Method {
+	case "POST", "PUT", "PATCH":
+		return r.ContentLength != 0
+	}
+	return false
+}
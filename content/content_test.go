@@ -0,0 +1,42 @@
+package content
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_RequireMatching(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{"foo":"bar"}`))
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+	w := httptest.NewRecorder()
+
+	if Require("application/json")(context.Background(), w, r).Err() != nil {
+		t.Error("JSON request should be allowed")
+	}
+}
+
+func Test_RequireMismatch(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", strings.NewReader("foo=bar"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	if Require("application/json")(context.Background(), w, r).Err() == nil {
+		t.Error("form request should be rejected")
+	}
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Error("Invalid response status: ", w.Code)
+	}
+}
+
+func Test_RequireWithoutBody(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+
+	if Require("application/json")(context.Background(), w, r).Err() != nil {
+		t.Error("GET request should be allowed")
+	}
+}
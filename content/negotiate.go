@@ -0,0 +1,107 @@
+package content
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chrisolsen/quincy"
+)
+
+type negotiated string
+
+// Negotiate returns a middleware that selects the offered content type best matching
+// the Accept header of the request, honoring the quality values, and stores it on
+// the context. A request without an Accept header is given the first offer, while
+// one accepting none of the offers is rejected with a 406.
+//	q := quincy.New(content.Negotiate("application/json", "application/xml"))
+func Negotiate(offers ...string) quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		accept := r.Header.Get("Accept")
+		if accept == "" && len(offers) > 0 {
+			return quincy.WithValue(c, negotiated(offers[0]))
+		}
+
+		ranges := parseAccept(accept)
+		best, bestQ := "", 0.0
+		for _, offer := range offers {
+			if q := quality(ranges, offer); q > bestQ {
+				best, bestQ = offer, q
+			}
+		}
+		if best == "" {
+			return quincy.Abort(c, w, http.StatusNotAcceptable)
+		}
+		return quincy.WithValue(c, negotiated(best))
+	}
+}
+
+// Type returns the content type selected by Negotiate, or an empty string if none
+// was selected
+//	switch content.Type(c) {
+//	case "application/xml":
+//		xml.NewEncoder(w).Encode(v)
+//	default:
+//		json.NewEncoder(w).Encode(v)
+//	}
+func Type(c context.Context) string {
+	t, _ := quincy.Value[negotiated](c)
+	return string(t)
+}
+
+// mediaRange is a single entry of an Accept header
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// parses the media ranges of an Accept header
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		typ, subtype, _ := strings.Cut(strings.ToLower(strings.TrimSpace(params[0])), "/")
+		if typ == "" {
+			continue
+		}
+		if subtype == "" {
+			subtype = "*"
+		}
+
+		mr := mediaRange{typ: typ, subtype: subtype, q: 1}
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if k == "q" {
+				if q, err := strconv.ParseFloat(v, 64); err == nil {
+					mr.q = q
+				}
+			}
+		}
+		ranges = append(ranges, mr)
+	}
+	return ranges
+}
+
+// returns the quality of the offer given by the most specific media range that
+// matches it, or zero if none match
+func quality(ranges []mediaRange, offer string) float64 {
+	typ, subtype, _ := strings.Cut(strings.ToLower(offer), "/")
+
+	q, specificity := 0.0, -1
+	for _, mr := range ranges {
+		s := -1
+		switch {
+		case mr.typ == typ && mr.subtype == subtype:
+			s = 2
+		case mr.typ == typ && mr.subtype == "*":
+			s = 1
+		case mr.typ == "*" && mr.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = mr.q, s
+		}
+	}
+	return q
+}
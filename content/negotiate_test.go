@@ -0,0 +1,48 @@
+package content
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func negotiate(accept string) (string, int) {
+	r := httptest.NewRequest("GET", "/", nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+
+	c := Negotiate("application/json", "application/xml")(context.Background(), w, r)
+	return Type(c), w.Code
+}
+
+func Test_Negotiate(t *testing.T) {
+	tests := map[string]string{
+		"":                 "application/json",
+		"application/xml":  "application/xml",
+		"application/json": "application/json",
+		"*/*":              "application/json",
+		"application/json;q=0.5, application/xml":     "application/xml",
+		"application/*;q=0.2, application/json;q=0.1": "application/xml",
+		"text/html, */*;q=0.1":                        "application/json",
+	}
+
+	for accept, expected := range tests {
+		if typ, _ := negotiate(accept); typ != expected {
+			t.Errorf("invalid type for %q: %s", accept, typ)
+		}
+	}
+}
+
+func Test_NegotiateNotAcceptable(t *testing.T) {
+	typ, code := negotiate("text/html, application/json;q=0")
+
+	if typ != "" {
+		t.Error("no type should be selected: ", typ)
+	}
+	if code != http.StatusNotAcceptable {
+		t.Error("Invalid response status: ", code)
+	}
+}
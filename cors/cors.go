@@ -0,0 +1,106 @@
+package cors
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// Config defines the cross-origin requests that are allowed
+type Config struct {
+	// Origins lists the allowed origins, such as https://example.com, with "*"
+	// allowing any origin
+	Origins []string
+
+	// Methods lists the allowed methods, defaulting to GET, HEAD and POST
+	Methods []string
+
+	// Headers lists the request headers that are allowed beyond the simple headers
+	Headers []string
+
+	// Credentials allows cookies and authorization headers to be sent. Since
+	// browsers reject a wildcard origin for credentialed requests, the origin of
+	// the request is reflected instead of "*".
+	Credentials bool
+
+	// MaxAge is how long the result of a preflight request can be cached
+	MaxAge time.Duration
+}
+
+// Allow returns a middleware that sets the CORS headers for requests from the
+// allowed origins. Preflight requests are answered with a 204 and end the chain
+// so no handler is run, while all other requests continue through it.
+//	q := quincy.New(cors.Allow(cors.Config{
+//		Origins: []string{"https://example.com"},
+//		Methods: []string{"GET", "POST", "DELETE"},
+//	}))
+func Allow(cfg Config) quincy.Middleware {
+	methods := cfg.Methods
+	if len(methods) == 0 {
+		methods = []string{"GET", "HEAD", "POST"}
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(cfg.Headers, ", ")
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != ""
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin != "" && cfg.allowed(origin) {
+			if cfg.Credentials || !cfg.wildcard() {
+				h.Set("Access-Control-Allow-Origin", origin)
+			} else {
+				h.Set("Access-Control-Allow-Origin", "*")
+			}
+			if cfg.Credentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if preflight {
+				h.Set("Access-Control-Allow-Methods", allowMethods)
+				if allowHeaders != "" {
+					h.Set("Access-Control-Allow-Headers", allowHeaders)
+				}
+				if cfg.MaxAge > 0 {
+					h.Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+				}
+			}
+		}
+
+		if preflight {
+			w.WriteHeader(http.StatusNoContent)
+			return quincy.Stop(c)
+		}
+		return c
+	}
+}
+
+// reports whether any origin is allowed
+func (cfg Config) wildcard() bool {
+	for _, o := range cfg.Origins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// reports whether the origin is within the allowed list
+func (cfg Config) allowed(origin string) bool {
+	for _, o := range cfg.Origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
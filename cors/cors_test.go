@@ -0,0 +1,94 @@
+package cors
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/testutil"
+)
+
+var config = Config{
+	Origins: []string{"https://example.com"},
+	Methods: []string{"GET", "POST", "DELETE"},
+	Headers: []string{"Content-Type"},
+	MaxAge:  time.Hour,
+}
+
+func Test_Preflight(t *testing.T) {
+	r := httptest.NewRequest("OPTIONS", "/accounts", nil)
+	r.Header.Set("Origin", "https://example.com")
+	r.Header.Set("Access-Control-Request-Method", "DELETE")
+	w := httptest.NewRecorder()
+
+	q := quincy.New(Allow(config))
+	q.ContextFunc = testutil.StubContext
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called for a preflight request")
+	})
+	fn(w, r)
+
+	if w.Code != http.StatusNoContent {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://example.com" {
+		t.Error("invalid allowed origin: ", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if w.Header().Get("Access-Control-Allow-Methods") != "GET, POST, DELETE" {
+		t.Error("invalid allowed methods: ", w.Header().Get("Access-Control-Allow-Methods"))
+	}
+	if w.Header().Get("Access-Control-Max-Age") != "3600" {
+		t.Error("invalid max age: ", w.Header().Get("Access-Control-Max-Age"))
+	}
+}
+
+func Test_SimpleRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/accounts", nil)
+	r.Header.Set("Origin", "https://example.com")
+	w := httptest.NewRecorder()
+
+	c := Allow(config)(context.Background(), w, r)
+
+	if c.Err() != nil {
+		t.Error("chain should continue")
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://example.com" {
+		t.Error("invalid allowed origin: ", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if w.Header().Get("Vary") != "Origin" {
+		t.Error("missing Vary header")
+	}
+	if w.Header().Get("Access-Control-Allow-Methods") != "" {
+		t.Error("methods should only be set for preflight requests")
+	}
+}
+
+func Test_DisallowedOrigin(t *testing.T) {
+	r := httptest.NewRequest("GET", "/accounts", nil)
+	r.Header.Set("Origin", "https://evil.com")
+	w := httptest.NewRecorder()
+
+	Allow(config)(context.Background(), w, r)
+
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("origin should not be allowed")
+	}
+}
+
+func Test_CredentialsWithWildcard(t *testing.T) {
+	r := httptest.NewRequest("GET", "/accounts", nil)
+	r.Header.Set("Origin", "https://example.com")
+	w := httptest.NewRecorder()
+
+	Allow(Config{Origins: []string{"*"}, Credentials: true})(context.Background(), w, r)
+
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://example.com" {
+		t.Error("origin should be reflected for credentialed requests")
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Error("missing credentials header")
+	}
+}
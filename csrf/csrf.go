@@ -0,0 +1,149 @@
+package csrf
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// Config defines how the token is issued and where it is read from, with empty
+// values being replaced by the defaults
+type Config struct {
+	// Key signs the token cookie and is required
+	Key []byte
+
+	// Cookie is the name of the cookie holding the token and defaults to "_csrf"
+	Cookie string
+
+	// Header is the request header checked for the token and defaults to
+	// "X-CSRF-Token"
+	Header string
+
+	// Field is the form field checked for the token when the header is missing and
+	// defaults to "csrf_token"
+	Field string
+
+	// MaxAge is how long the token cookie lasts, after which a new token is issued,
+	// and defaults to 12 hours
+	MaxAge time.Duration
+
+	// SameSite defaults to http.SameSiteLaxMode
+	SameSite http.SameSite
+}
+
+type state struct {
+	cfg   *Config
+	token string
+}
+
+// Protect returns a middleware that issues each client a token, held in a signed
+// cookie, and rejects POST, PUT, PATCH and DELETE requests with a 403 unless the
+// token is sent back in the header or form field. The token is made available to
+// handlers and templates with Token.
+//	q := quincy.New(csrf.Protect(csrf.Config{Key: key}))
+func Protect(cfg Config) quincy.Middleware {
+	if len(cfg.Key) == 0 {
+		panic("csrf: a key is required")
+	}
+	if cfg.Cookie == "" {
+		cfg.Cookie = "_csrf"
+	}
+	if cfg.Header == "" {
+		cfg.Header = "X-CSRF-Token"
+	}
+	if cfg.Field == "" {
+		cfg.Field = "csrf_token"
+	}
+	if cfg.MaxAge == 0 {
+		cfg.MaxAge = 12 * time.Hour
+	}
+	if cfg.SameSite == 0 {
+		cfg.SameSite = http.SameSiteLaxMode
+	}
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		st := &state{cfg: &cfg}
+		if cookie, err := r.Cookie(cfg.Cookie); err == nil {
+			st.token, _ = verify(cfg.Key, cookie.Value)
+		}
+
+		switch r.Method {
+		case "POST", "PUT", "PATCH", "DELETE":
+			sent := r.Header.Get(cfg.Header)
+			if sent == "" {
+				sent = r.PostFormValue(cfg.Field)
+			}
+			if st.token == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(st.token)) != 1 {
+				return quincy.Abort(c, w, http.StatusForbidden)
+			}
+		default:
+			if st.token == "" {
+				issue(w, r, st)
+			}
+		}
+		return quincy.WithValue(c, st)
+	}
+}
+
+// Token returns the token to be sent back with unsafe requests, or an empty string
+// if Protect did not run
+//	data.CSRFToken = csrf.Token(c)
+func Token(c context.Context) string {
+	st, _ := quincy.Value[*state](c)
+	if st == nil {
+		return ""
+	}
+	return st.token
+}
+
+// Rotate issues a new token, which should be done when the privileges of the
+// client change, such as on login, returning the new token or an empty string if
+// Protect did not run
+func Rotate(c context.Context, w http.ResponseWriter, r *http.Request) string {
+	st, _ := quincy.Value[*state](c)
+	if st == nil {
+		return ""
+	}
+	issue(w, r, st)
+	return st.token
+}
+
+// generates a new token and sets it as the cookie
+func issue(w http.ResponseWriter, r *http.Request, st *state) {
+	b := make([]byte, 32)
+	rand.Read(b)
+	st.token = base64.RawURLEncoding.EncodeToString(b)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     st.cfg.Cookie,
+		Value:    st.token + "." + sign(st.cfg.Key, st.token),
+		Path:     "/",
+		MaxAge:   int(st.cfg.MaxAge / time.Second),
+		Secure:   r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https"),
+		HttpOnly: true,
+		SameSite: st.cfg.SameSite,
+	})
+}
+
+// returns the token held by the cookie value if its signature is valid
+func verify(key []byte, value string) (string, bool) {
+	token, sig, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(sign(key, token))) {
+		return "", false
+	}
+	return token, true
+}
+
+func sign(key []byte, token string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,126 @@
+package csrf
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+var key = []byte("secret")
+
+// returns the token cookie issued by a GET request along with the token
+func issued(t *testing.T) (*http.Cookie, string) {
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	c := Protect(Config{Key: key})(context.Background(), w, r)
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatal("expected a token cookie")
+	}
+	return cookies[0], Token(c)
+}
+
+func Test_Issue(t *testing.T) {
+	cookie, token := issued(t)
+
+	if token == "" {
+		t.Error("token not set on the context")
+	}
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Error("invalid cookie attributes")
+	}
+	if got, ok := verify(key, cookie.Value); !ok || got != token {
+		t.Error("cookie does not hold the token")
+	}
+}
+
+func Test_Reissue(t *testing.T) {
+	cookie, token := issued(t)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	c := Protect(Config{Key: key})(context.Background(), w, r)
+
+	if Token(c) != token {
+		t.Error("existing token should be kept")
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("cookie should not be set again")
+	}
+}
+
+func Test_MissingToken(t *testing.T) {
+	cookie, _ := issued(t)
+
+	r := httptest.NewRequest("POST", "/", nil)
+	r.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	c := Protect(Config{Key: key})(context.Background(), w, r)
+
+	if w.Code != 403 || c.Err() == nil {
+		t.Error("request should be rejected")
+	}
+}
+
+func Test_ForgedCookie(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "_csrf", Value: "token.forged"})
+	r.Header.Set("X-CSRF-Token", "token")
+	w := httptest.NewRecorder()
+	Protect(Config{Key: key})(context.Background(), w, r)
+
+	if w.Code != 403 {
+		t.Error("expected 403, got ", w.Code)
+	}
+}
+
+func Test_ValidHeader(t *testing.T) {
+	cookie, token := issued(t)
+
+	r := httptest.NewRequest("POST", "/", nil)
+	r.AddCookie(cookie)
+	r.Header.Set("X-CSRF-Token", token)
+	w := httptest.NewRecorder()
+	c := Protect(Config{Key: key})(context.Background(), w, r)
+
+	if w.Code != 200 || c.Err() != nil {
+		t.Error("request should proceed")
+	}
+}
+
+func Test_ValidField(t *testing.T) {
+	cookie, token := issued(t)
+
+	form := url.Values{"csrf_token": {token}}
+	r := httptest.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	c := Protect(Config{Key: key})(context.Background(), w, r)
+
+	if w.Code != 200 || c.Err() != nil {
+		t.Error("request should proceed")
+	}
+}
+
+func Test_Rotate(t *testing.T) {
+	cookie, token := issued(t)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	c := Protect(Config{Key: key})(context.Background(), w, r)
+
+	rotated := Rotate(c, w, r)
+	if rotated == "" || rotated == token || Token(c) != rotated {
+		t.Error("token not rotated")
+	}
+	if len(w.Result().Cookies()) != 1 {
+		t.Error("rotated token cookie not set")
+	}
+}
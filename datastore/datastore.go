@@ -0,0 +1,113 @@
+package datastore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/chrisolsen/quincy"
+	aedatastore "google.golang.org/appengine/datastore"
+)
+
+// ErrNoTransaction is returned by Commit when the context does not hold a
+// transaction started by Transaction
+var ErrNoTransaction = errors.New("datastore: no transaction in context")
+
+// errRollback ends the transaction without committing it when the request did
+// not complete
+var errRollback = errors.New("datastore: transaction rolled back")
+
+// runInTransaction runs the transaction of each request. The tests fake it to
+// decide whether the commit succeeds, which a real transaction leaves to the
+// datastore.
+var runInTransaction = aedatastore.RunInTransaction
+
+// txn bridges the transaction, which datastore runs within a closure, to the rest
+// of the chain, which runs after the middleware returns
+type txn struct {
+	outcome chan error
+	result  chan error
+	once    sync.Once
+	err     error
+}
+
+// ends the transaction, rolling it back if outcome is not nil, and returns the
+// result of committing it
+func (t *txn) end(outcome error) error {
+	t.once.Do(func() {
+		t.outcome <- outcome
+		t.err = <-t.result
+	})
+	return t.err
+}
+
+// Transaction returns a middleware that runs the rest of the chain within a
+// datastore transaction, replacing the context with the transactional one. The
+// transaction is committed once the request completes, or rolled back if the
+// chain was aborted or the handler panicked.
+//
+// Since a middleware returns before the chain carries on, the closure given to
+// datastore.RunInTransaction is run on its own goroutine and waits for the request
+// to complete. A transaction that fails due to contention can't be retried as the
+// handler has already run, so opts.Attempts is ignored and a single attempt is
+// made. As the commit happens after the handler has written its response, a
+// handler that must report a failed commit should call Commit before responding.
+//	q := quincy.New(datastore.Transaction(&aedatastore.TransactionOptions{XG: true}))
+func Transaction(opts *aedatastore.TransactionOptions) quincy.Middleware {
+	single := aedatastore.TransactionOptions{Attempts: 1}
+	if opts != nil {
+		single.XG = opts.XG
+		single.ReadOnly = opts.ReadOnly
+	}
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		t := &txn{outcome: make(chan error, 1), result: make(chan error, 1)}
+		if !quincy.Defer(c, func() {
+			outcome := quincy.Err(c)
+			if outcome != nil {
+				outcome = errRollback
+			}
+			t.end(outcome)
+		}) {
+			return quincy.Fail(c, http.StatusInternalServerError, errors.New("datastore: Transaction must be run by Then or Handle"))
+		}
+
+		started := make(chan context.Context, 1)
+		go func() {
+			err := runInTransaction(c, func(tc context.Context) error {
+				started <- tc
+				return <-t.outcome
+			}, &single)
+			if err == errRollback {
+				err = nil
+			}
+			t.result <- err
+		}()
+
+		select {
+		case tc := <-started:
+			return quincy.WithValue(tc, t)
+		case err := <-t.result:
+			// the transaction could not be started
+			t.once.Do(func() { t.err = err })
+			return quincy.Fail(c, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// Commit commits the transaction started by Transaction, returning the error of
+// the commit, such as datastore.ErrConcurrentTransaction. The transaction is not
+// committed again once the request completes, and later calls return the same
+// error.
+//	if err := datastore.Commit(c); err != nil {
+//		http.Error(w, "please try again", http.StatusConflict)
+//		return
+//	}
+func Commit(c context.Context) error {
+	t, _ := quincy.Value[*txn](c)
+	if t == nil {
+		return ErrNoTransaction
+	}
+	return t.end(nil)
+}
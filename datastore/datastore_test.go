@@ -0,0 +1,94 @@
+package datastore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/testutil"
+	aedatastore "google.golang.org/appengine/datastore"
+)
+
+type txKey struct{}
+
+// fakes a datastore transaction, recording whether it was committed
+func fake(committed *bool, commitErr error) {
+	runInTransaction = func(c context.Context, f func(context.Context) error, opts *aedatastore.TransactionOptions) error {
+		if err := f(context.WithValue(c, txKey{}, true)); err != nil {
+			return err
+		}
+		*committed = true
+		return commitErr
+	}
+}
+
+func serve(fn quincy.HandlerFunc, mw ...quincy.Middleware) {
+	q := quincy.New(append([]quincy.Middleware{Transaction(nil)}, mw...)...)
+	q.ContextFunc = testutil.StubContext
+	q.Then(fn)(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil))
+}
+
+func Test_Transaction_Commit(t *testing.T) {
+	var committed, inTx bool
+	fake(&committed, nil)
+
+	serve(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		inTx = c.Value(txKey{}) != nil
+	})
+
+	if !inTx {
+		t.Error("handler not given the transactional context")
+	}
+	if !committed {
+		t.Error("transaction not committed")
+	}
+}
+
+func Test_Transaction_Rollback(t *testing.T) {
+	var committed bool
+	fake(&committed, nil)
+
+	abort := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return quincy.Abort(c, w, http.StatusForbidden)
+	}
+	serve(nil, abort)
+
+	if committed {
+		t.Error("aborted transaction should be rolled back")
+	}
+}
+
+func Test_Transaction_Panic(t *testing.T) {
+	var committed bool
+	fake(&committed, nil)
+
+	serve(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		panic("foobar")
+	}, quincy.Recover())
+
+	if committed {
+		t.Error("panicked transaction should be rolled back")
+	}
+}
+
+func Test_Commit(t *testing.T) {
+	var committed bool
+	conflict := errors.New("conflict")
+	fake(&committed, conflict)
+
+	var first, second error
+	serve(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		first = Commit(c)
+		second = Commit(c)
+	})
+
+	if first != conflict || second != conflict {
+		t.Error("commit error not returned: ", first, second)
+	}
+	if Commit(context.Background()) != ErrNoTransaction {
+		t.Error("expected ErrNoTransaction")
+	}
+}
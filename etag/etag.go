@@ -0,0 +1,143 @@
+package etag
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// Conditional returns a middleware that buffers the response of GET and HEAD
+// requests to tag it with an ETag computed from its body. When the If-None-Match
+// header of the request matches the tag a 304 is written in place of the body. An
+// ETag set by the handler is used as is, and responses other than a 200 are
+// written unchanged. Nothing buffered is written when the request fails, such as
+// with a recovered panic, since its error response has already been written. The
+// buffered response can only be released once the request completes with Then
+// and Handle, so chains run with Run are left untagged.
+//	q := quincy.New(etag.Conditional())
+func Conditional() quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			return c
+		}
+
+		bw := &bufferedWriter{Wrapper: quincy.Wrapper{ResponseWriter: w}, c: c, r: r}
+		if !quincy.Defer(c, bw.finish) {
+			return c
+		}
+		return quincy.WithWriter(c, bw)
+	}
+}
+
+// bufferedWriter holds on to the response until the handler returns so the ETag
+// can be computed
+type bufferedWriter struct {
+	quincy.Wrapper
+	c           context.Context
+	r           *http.Request
+	status      int
+	body        []byte
+	passthrough bool
+}
+
+func (b *bufferedWriter) WriteHeader(status int) {
+	if b.passthrough {
+		b.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	if b.passthrough {
+		return b.ResponseWriter.Write(p)
+	}
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	b.body = append(b.body, p...)
+	return len(p), nil
+}
+
+// Flush writes out the buffered response and stops buffering, since a flushing
+// handler is streaming its response and can't be tagged
+func (b *bufferedWriter) Flush() {
+	b.release()
+	b.Wrapper.Flush()
+}
+
+// Hijack takes over the connection if the underlying writer supports it, dropping
+// anything buffered since the handler now owns the connection
+func (b *bufferedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := b.Wrapper.Hijack()
+	if err == nil {
+		b.passthrough = true
+		b.body = nil
+	}
+	return conn, rw, err
+}
+
+// tags the buffered response, writing a 304 when the client already holds it
+func (b *bufferedWriter) finish() {
+	if quincy.Err(b.c) != nil {
+		b.passthrough = true
+		b.body = nil
+		return
+	}
+	if b.passthrough || b.status != http.StatusOK {
+		b.release()
+		return
+	}
+
+	h := b.Header()
+	tag := h.Get("ETag")
+	if tag == "" {
+		sum := sha1.Sum(b.body)
+		tag = `"` + hex.EncodeToString(sum[:]) + `"`
+		h.Set("ETag", tag)
+	}
+
+	if match(b.r.Header.Get("If-None-Match"), tag) {
+		h.Del("Content-Type")
+		h.Del("Content-Length")
+		b.status = http.StatusNotModified
+		b.body = nil
+	}
+	b.release()
+}
+
+// writes the buffered status and body to the underlying writer
+func (b *bufferedWriter) release() {
+	if b.passthrough {
+		return
+	}
+	b.passthrough = true
+	if b.status != 0 {
+		b.ResponseWriter.WriteHeader(b.status)
+	}
+	if len(b.body) > 0 {
+		b.ResponseWriter.Write(b.body)
+	}
+	b.body = nil
+}
+
+// reports whether the If-None-Match header matches the tag, using the weak
+// comparison that conditional GET requests call for
+func match(header, tag string) bool {
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package etag
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/testutil"
+)
+
+func serve(r *http.Request, fn quincy.HandlerFunc) *httptest.ResponseRecorder {
+	q := quincy.New(Conditional())
+	q.ContextFunc = testutil.StubContext
+
+	w := httptest.NewRecorder()
+	q.Then(fn)(w, r)
+	return w
+}
+
+func hello(c context.Context, w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("hello world"))
+}
+
+func Test_Conditional(t *testing.T) {
+	w := serve(httptest.NewRequest("GET", "/", nil), hello)
+
+	tag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || tag == "" {
+		t.Error("expected a 200 with an ETag: ", w.Code, tag)
+		return
+	}
+	if w.Body.String() != "hello world" {
+		t.Error("invalid body: ", w.Body.String())
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("If-None-Match", tag)
+	w = serve(r, hello)
+
+	if w.Code != http.StatusNotModified {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Error("304 response should not have a body")
+	}
+}
+
+func Test_ConditionalExistingTag(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("If-None-Match", `W/"v1"`)
+	w := serve(r, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		hello(c, w, r)
+	})
+
+	if w.Code != http.StatusNotModified {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if w.Header().Get("ETag") != `"v1"` {
+		t.Error("existing ETag was replaced: ", w.Header().Get("ETag"))
+	}
+}
+
+func Test_ConditionalIgnoresErrors(t *testing.T) {
+	w := serve(httptest.NewRequest("GET", "/", nil), func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	if w.Code != http.StatusNotFound {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if w.Header().Get("ETag") != "" {
+		t.Error("non-200 responses should not be tagged")
+	}
+}
+
+func Test_ConditionalPanic(t *testing.T) {
+	q := quincy.New(quincy.Recover(), Conditional())
+	q.ContextFunc = testutil.StubContext
+
+	w := httptest.NewRecorder()
+	q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial secret"))
+		panic("failure")
+	})(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Error("invalid response status: ", w.Code)
+	}
+	if w.Body.Len() != 0 || w.Header().Get("ETag") != "" {
+		t.Error("buffered response should not be written after a panic: ", w.Body.String())
+	}
+}
+
+func Test_ConditionalRun(t *testing.T) {
+	q := quincy.New(Conditional(), func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		hello(c, w, r)
+		return c
+	})
+
+	w := httptest.NewRecorder()
+	q.Run(context.Background(), w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Header().Get("ETag") != "" {
+		t.Error("response should not be tagged without Then")
+	}
+	if w.Body.String() != "hello world" {
+		t.Error("body written through Run was lost: ", w.Body.String())
+	}
+}
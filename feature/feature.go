@@ -0,0 +1,80 @@
+package feature
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// Evaluator reports whether a flag is on for the request, and is given the
+// context so it can look up per user overrides
+type Evaluator func(context.Context, *http.Request) bool
+
+// flags holds the flags evaluated for a request, so each is evaluated once
+type flags struct {
+	mu sync.Mutex
+	on map[string]bool
+}
+
+// Flag returns a middleware that evaluates the flag and stores its state on the
+// context to be read with On
+//	q := quincy.New(feature.Flag("new-checkout", rollout.IsOn))
+func Flag(name string, isOn Evaluator) quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		c, _ = evaluate(c, r, name, isOn)
+		return c
+	}
+}
+
+// Gate returns a middleware like Flag that also aborts the chain with a 404 when
+// the flag is off, hiding the route until it is turned on
+//	q := quincy.New(feature.Gate("reports", rollout.IsOn))
+func Gate(name string, isOn Evaluator) quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		c, on := evaluate(c, r, name, isOn)
+		if !on {
+			return quincy.Abort(c, w, http.StatusNotFound)
+		}
+		return c
+	}
+}
+
+// On reports whether the flag was evaluated as on for the request, which is false
+// for flags that were not evaluated by Flag or Gate
+//	if feature.On(c, "new-checkout") {
+//		...
+//	}
+func On(c context.Context, name string) bool {
+	f, _ := quincy.Value[*flags](c)
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.on[name]
+}
+
+// returns the state of the flag, evaluating it only if it has not already been
+// evaluated for the request
+func evaluate(c context.Context, r *http.Request, name string, isOn Evaluator) (context.Context, bool) {
+	f, _ := quincy.Value[*flags](c)
+	if f == nil {
+		f = &flags{on: map[string]bool{}}
+		c = quincy.WithValue(c, f)
+	}
+
+	f.mu.Lock()
+	on, ok := f.on[name]
+	f.mu.Unlock()
+	if ok {
+		return c, on
+	}
+
+	on = isOn(c, r)
+	f.mu.Lock()
+	f.on[name] = on
+	f.mu.Unlock()
+	return c, on
+}
@@ -0,0 +1,55 @@
+package feature
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Flag(t *testing.T) {
+	calls := 0
+	isOn := func(c context.Context, r *http.Request) bool {
+		calls++
+		return true
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	c := Flag("beta", isOn)(context.Background(), w, r)
+	c = Gate("beta", isOn)(c, w, r)
+
+	if !On(c, "beta") || c.Err() != nil {
+		t.Error("flag should be on")
+	}
+	if On(c, "other") {
+		t.Error("unevaluated flag should be off")
+	}
+	if calls != 1 {
+		t.Error("flag should be evaluated once, got ", calls)
+	}
+}
+
+func Test_Gate_Off(t *testing.T) {
+	off := func(c context.Context, r *http.Request) bool { return false }
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	c := Gate("beta", off)(context.Background(), w, r)
+
+	if w.Code != 404 || c.Err() == nil {
+		t.Error("expected the chain to be aborted with 404, got ", w.Code)
+	}
+}
+
+func Test_Flag_Off(t *testing.T) {
+	off := func(c context.Context, r *http.Request) bool { return false }
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	c := Flag("beta", off)(context.Background(), w, r)
+
+	if On(c, "beta") || c.Err() != nil || w.Code != 200 {
+		t.Error("off flag should not abort the chain")
+	}
+}
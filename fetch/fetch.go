@@ -0,0 +1,63 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+	"google.golang.org/appengine/urlfetch"
+)
+
+// newClient binds a URL Fetch client to the context it is given, which the tests
+// check is the request context, with its deadline, by recording it on a fake
+// transport
+var newClient = urlfetch.Client
+
+type config struct {
+	deadline time.Duration
+}
+
+// Option configures the URL Fetch middleware
+type Option func(*config)
+
+// Deadline sets the deadline of the outbound calls made by the client, which are
+// otherwise given the deadline of the request context, if it has one. The earlier
+// of the two applies when both are set.
+func Deadline(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.deadline = d
+	}
+}
+
+// Client returns a middleware that creates an http.Client making its calls through
+// App Engine URL Fetch with the request context, and stores it on the context to
+// be read with HTTPClient, so handlers and middleware share a client bound to the
+// deadline of the request.
+//	q := quincy.New(fetch.Client(fetch.Deadline(5 * time.Second)))
+func Client(opts ...Option) quincy.Middleware {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		fc := c
+		if cfg.deadline > 0 {
+			var cancel context.CancelFunc
+			fc, cancel = context.WithTimeout(c, cfg.deadline)
+			quincy.Defer(c, cancel)
+		}
+		return quincy.WithValue(c, newClient(fc))
+	}
+}
+
+// HTTPClient returns the client created by Client, falling back to a URL Fetch
+// client using c when Client did not run
+//	res, err := fetch.HTTPClient(c).Get("https://example.com/")
+func HTTPClient(c context.Context) *http.Client {
+	if client, _ := quincy.Value[*http.Client](c); client != nil {
+		return client
+	}
+	return newClient(c)
+}
@@ -0,0 +1,67 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+)
+
+type transport struct {
+	c context.Context
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return nil, http.ErrNotSupported
+}
+
+func init() {
+	newClient = func(c context.Context) *http.Client {
+		return &http.Client{Transport: &transport{c}}
+	}
+}
+
+// returns the client the handler was given
+func serve(c context.Context, mw quincy.Middleware) *http.Client {
+	var client *http.Client
+	q := quincy.New(mw)
+	q.ContextFunc = func(r *http.Request) context.Context {
+		return c
+	}
+	q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		client = HTTPClient(c)
+	})(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	return client
+}
+
+func Test_Client(t *testing.T) {
+	c, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	client := serve(c, Client())
+
+	if client == nil {
+		t.Fatal("client not set")
+	}
+	deadline, ok := client.Transport.(*transport).c.Deadline()
+	if want, _ := c.Deadline(); !ok || !deadline.Equal(want) {
+		t.Error("client should use the request deadline")
+	}
+}
+
+func Test_Client_Deadline(t *testing.T) {
+	client := serve(context.Background(), Client(Deadline(time.Second)))
+
+	deadline, ok := client.Transport.(*transport).c.Deadline()
+	if !ok || time.Until(deadline) > time.Second {
+		t.Error("client should use the configured deadline")
+	}
+}
+
+func Test_HTTPClient_Fallback(t *testing.T) {
+	if HTTPClient(context.Background()) == nil {
+		t.Error("expected a fallback client")
+	}
+}
@@ -0,0 +1,85 @@
+package gae
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// the addresses App Engine reports for requests made by its cron and task queue
+// services
+var internalIPs = map[string]bool{
+	"0.1.0.1": true,
+	"0.1.0.2": true,
+}
+
+type config struct {
+	checkSource bool
+}
+
+// Option configures the cron and task middleware
+type Option func(*config)
+
+// CheckSource also requires the X-AppEngine-User-IP header to hold the internal
+// address App Engine uses for cron and task requests
+func CheckSource() Option {
+	return func(cfg *config) {
+		cfg.checkSource = true
+	}
+}
+
+// CronOnly returns a middleware that aborts the chain with a 403 unless the request
+// was made by the App Engine cron service, as shown by the X-Appengine-Cron header,
+// which App Engine strips from external requests
+//	http.HandleFunc("/cron/cleanup", quincy.New(gae.CronOnly()).Then(cleanup))
+func CronOnly(opts ...Option) quincy.Middleware {
+	return only(func(r *http.Request) bool {
+		return r.Header.Get("X-Appengine-Cron") == "true"
+	}, opts)
+}
+
+// TaskOnly returns a middleware that aborts the chain with a 403 unless the request
+// was made by an App Engine task queue, as shown by the X-AppEngine-QueueName
+// header, which App Engine strips from external requests
+//	http.HandleFunc("/tasks/email", quincy.New(gae.TaskOnly()).Then(sendEmail))
+func TaskOnly(opts ...Option) quincy.Middleware {
+	return only(func(r *http.Request) bool {
+		return r.Header.Get("X-AppEngine-QueueName") != ""
+	}, opts)
+}
+
+// returns a middleware rejecting requests that fail the check
+func only(check func(*http.Request) bool, opts []Option) quincy.Middleware {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		if !check(r) || (cfg.checkSource && !internalIPs[r.Header.Get("X-AppEngine-User-IP")]) {
+			return quincy.Abort(c, w, http.StatusForbidden)
+		}
+		return c
+	}
+}
+
+// InboundApp returns a middleware that aborts the chain with a 403 unless the
+// request was made by one of the allowed App Engine apps, as shown by the
+// X-Appengine-Inbound-Appid header, which App Engine only sets for requests made
+// by other apps. External requests, which have no app ID, are always rejected.
+//	q := quincy.New(gae.InboundApp("billing-service", "reports-service"))
+func InboundApp(allowed ...string) quincy.Middleware {
+	apps := make(map[string]bool, len(allowed))
+	for _, app := range allowed {
+		apps[app] = true
+	}
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		app := r.Header.Get("X-Appengine-Inbound-Appid")
+		if app == "" || !apps[app] {
+			return quincy.Abort(c, w, http.StatusForbidden)
+		}
+		return c
+	}
+}
@@ -0,0 +1,67 @@
+package gae
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chrisolsen/quincy"
+)
+
+func Test_Only(t *testing.T) {
+	tests := []struct {
+		mw      quincy.Middleware
+		headers map[string]string
+		allowed bool
+	}{
+		{CronOnly(), map[string]string{"X-Appengine-Cron": "true"}, true},
+		{CronOnly(), map[string]string{}, false},
+		{CronOnly(), map[string]string{"X-AppEngine-QueueName": "default"}, false},
+		{TaskOnly(), map[string]string{"X-AppEngine-QueueName": "default"}, true},
+		{TaskOnly(), map[string]string{"X-Appengine-Cron": "true"}, false},
+		{CronOnly(CheckSource()), map[string]string{"X-Appengine-Cron": "true", "X-AppEngine-User-IP": "0.1.0.2"}, true},
+		{CronOnly(CheckSource()), map[string]string{"X-Appengine-Cron": "true", "X-AppEngine-User-IP": "203.0.113.7"}, false},
+	}
+
+	for i, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		for k, v := range test.headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		c := test.mw(context.Background(), w, r)
+
+		if allowed := c.Err() == nil; allowed != test.allowed {
+			t.Errorf("test %d: expected allowed to be %v", i, test.allowed)
+		}
+		if !test.allowed && w.Code != 403 {
+			t.Errorf("test %d: expected 403, got %d", i, w.Code)
+		}
+	}
+}
+
+func Test_InboundApp(t *testing.T) {
+	tests := map[string]bool{
+		"billing":  true,
+		"":         false,
+		"reports":  false,
+		"Billing":  false,
+		"billing2": false,
+	}
+
+	for app, allowed := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if app != "" {
+			r.Header.Set("X-Appengine-Inbound-Appid", app)
+		}
+		w := httptest.NewRecorder()
+		c := InboundApp("billing")(context.Background(), w, r)
+
+		if (c.Err() == nil) != allowed {
+			t.Errorf("%q: expected allowed to be %v", app, allowed)
+		}
+		if !allowed && w.Code != 403 {
+			t.Errorf("%q: expected 403, got %d", app, w.Code)
+		}
+	}
+}
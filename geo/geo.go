@@ -0,0 +1,69 @@
+package geo
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// Unknown is the country of requests App Engine could not locate
+const Unknown = "unknown"
+
+// Geo holds the location App Engine derived from the IP of the request
+type Geo struct {
+	// Country is the ISO 3166-1 alpha-2 code of the country, or Unknown
+	Country string
+
+	// Region is the ISO 3166-2 code of the region within the country, if known
+	Region string
+
+	// City is the name of the city, if known
+	City string
+
+	// LatLong is the latitude and longitude of the city, separated by a comma, if
+	// known
+	LatLong string
+}
+
+// Info returns a middleware that reads the location headers App Engine adds to
+// each request and stores them on the context. Since App Engine strips these
+// headers from inbound requests, they can't be set by the client.
+//	q := quincy.New(geo.Info())
+func Info() quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		g := Geo{
+			Country: strings.ToUpper(r.Header.Get("X-AppEngine-Country")),
+			Region:  known(r.Header.Get("X-AppEngine-Region")),
+			City:    known(r.Header.Get("X-AppEngine-City")),
+			LatLong: known(r.Header.Get("X-AppEngine-CityLatLong")),
+		}
+		if g.Country == "" || g.Country == "ZZ" {
+			g.Country = Unknown
+		}
+		return quincy.WithValue(c, g)
+	}
+}
+
+// From returns the location of the request, with the country being Unknown if
+// Info did not run
+//	if geo.From(c).Country == "DE" {
+//		showCookieBanner = true
+//	}
+func From(c context.Context) Geo {
+	g, ok := quincy.Value[Geo](c)
+	if !ok {
+		g.Country = Unknown
+	}
+	return g
+}
+
+// returns the value, or an empty string for the "?" App Engine sends when the
+// value is not known
+func known(v string) string {
+	if v == "?" {
+		return ""
+	}
+	return v
+}
@@ -0,0 +1,38 @@
+package geo
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Info(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-AppEngine-Country", "ca")
+	r.Header.Set("X-AppEngine-Region", "ab")
+	r.Header.Set("X-AppEngine-City", "edmonton")
+	r.Header.Set("X-AppEngine-CityLatLong", "53.546125,-113.493823")
+	c := Info()(context.Background(), nil, r)
+
+	expected := Geo{Country: "CA", Region: "ab", City: "edmonton", LatLong: "53.546125,-113.493823"}
+	if g := From(c); g != expected {
+		t.Errorf("invalid geo info: %+v", g)
+	}
+}
+
+func Test_Info_Unknown(t *testing.T) {
+	for _, country := range []string{"", "ZZ"} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-AppEngine-Country", country)
+		r.Header.Set("X-AppEngine-City", "?")
+		c := Info()(context.Background(), nil, r)
+
+		if g := From(c); g.Country != Unknown || g.City != "" {
+			t.Errorf("%q: invalid geo info: %+v", country, g)
+		}
+	}
+
+	if From(context.Background()).Country != Unknown {
+		t.Error("country should be unknown without the middleware")
+	}
+}
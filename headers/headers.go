@@ -1,10 +1,10 @@
 package headers
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/chrisolsen/quincy"
-	"golang.org/x/net/context"
 )
 
 // Set sets the response header to the key and value provided
@@ -14,3 +14,29 @@ func Set(key, value string) quincy.Middleware {
 		return c
 	}
 }
+
+// the request headers that would let a conditional response be served
+var conditional = []string{
+	"If-Modified-Since",
+	"If-None-Match",
+	"If-Match",
+	"If-Unmodified-Since",
+	"If-Range",
+}
+
+// NoCache sets the response headers that stop browsers and proxies from caching
+// the response, and removes the conditional headers from the request so it can't
+// be answered with a 304
+//	q := quincy.New(headers.NoCache())
+func NoCache() quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		h := w.Header()
+		h.Set("Cache-Control", "no-store, no-cache, must-revalidate")
+		h.Set("Pragma", "no-cache")
+		h.Set("Expires", "0")
+		for _, key := range conditional {
+			r.Header.Del(key)
+		}
+		return c
+	}
+}
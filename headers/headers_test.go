@@ -1,6 +1,7 @@
 package headers
 
 import (
+	"context"
 	"net/http/httptest"
 	"testing"
 
@@ -22,3 +23,25 @@ func Test_SetHeaders(t *testing.T) {
 		return
 	}
 }
+
+func Test_NoCache(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("If-None-Match", `"abc"`)
+	r.Header.Set("If-Modified-Since", "Mon, 02 Jan 2006 15:04:05 GMT")
+	w := httptest.NewRecorder()
+	c := NoCache()(context.Background(), w, r)
+
+	h := w.Header()
+	if h.Get("Cache-Control") != "no-store, no-cache, must-revalidate" {
+		t.Error("invalid Cache-Control: ", h.Get("Cache-Control"))
+	}
+	if h.Get("Pragma") != "no-cache" || h.Get("Expires") != "0" {
+		t.Error("invalid Pragma or Expires")
+	}
+	if r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+		t.Error("conditional headers not removed")
+	}
+	if c.Err() != nil {
+		t.Error("chain should continue")
+	}
+}
@@ -0,0 +1,28 @@
+package health
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// Heartbeat returns a middleware that answers GET and HEAD requests for the path
+// with a 200 and ends the chain, so health checks skip the work of the remaining
+// middleware, such as authentication and logging. It should be added first.
+//	q := quincy.New(health.Heartbeat("/_ah/health"), logger.Log())
+func Heartbeat(path string) quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		if r.URL.Path != path || (r.Method != "GET" && r.Method != "HEAD") {
+			return c
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == "GET" {
+			w.Write([]byte("ok"))
+		}
+		return quincy.Stop(c)
+	}
+}
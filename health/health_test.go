@@ -0,0 +1,46 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/testutil"
+)
+
+func serve(r *http.Request) (*httptest.ResponseRecorder, bool) {
+	called := false
+	q := quincy.New(Heartbeat("/health"), func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		called = true
+		return c
+	})
+	q.ContextFunc = testutil.StubContext
+
+	w := httptest.NewRecorder()
+	q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {})(w, r)
+	return w, called
+}
+
+func Test_Heartbeat(t *testing.T) {
+	w, called := serve(httptest.NewRequest("GET", "/health", nil))
+
+	if w.Code != 200 || w.Body.String() != "ok" {
+		t.Error("invalid heartbeat response: ", w.Code, w.Body.String())
+	}
+	if called {
+		t.Error("downstream middleware should not be called")
+	}
+}
+
+func Test_Heartbeat_PassThrough(t *testing.T) {
+	for _, r := range []*http.Request{
+		httptest.NewRequest("GET", "/other", nil),
+		httptest.NewRequest("POST", "/health", nil),
+	} {
+		if _, called := serve(r); !called {
+			t.Error("downstream middleware should be called for ", r.Method, r.URL.Path)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package quincy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// JSONError writes an error response with a JSON body holding the message and
+// status
+//	quincy.JSONError(w, http.StatusNotFound, "account not found")
+func JSONError(w http.ResponseWriter, status int, msg string) {
+	b, _ := json.Marshal(struct {
+		Error  string `json:"error"`
+		Status int    `json:"status"`
+	}{msg, status})
+
+	h := w.Header()
+	h.Set("Content-Type", "application/json; charset=utf-8")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Del("Content-Length")
+	w.WriteHeader(status)
+	w.Write(append(b, '\n'))
+}
+
+// JSONErrors returns an OnError hook that responds with a JSON error, using the
+// status given to Fail, for requests whose Accept header prefers JSON over HTML.
+// Other requests are passed on to next, if it is not nil. No response is written
+// once one has been started, such as by Abort, since its header has already been
+// sent, or when the client has gone away.
+//	q.OnError(quincy.JSONErrors(nil))
+func JSONErrors(next ErrorFunc) ErrorFunc {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request, err error) {
+		if !prefersJSON(r) {
+			if next != nil {
+				next(c, w, r, err)
+			}
+			return
+		}
+		if rec, ok := w.(*StatusRecorder); ok && rec.Status() != 0 {
+			return
+		}
+		status := statusOf(c, err)
+		if status == StatusClientClosedRequest {
+			return
+		}
+		JSONError(w, status, http.StatusText(status))
+	}
+}
+
+// reports whether the Accept header gives JSON a quality above zero and at least
+// that of HTML
+func prefersJSON(r *http.Request) bool {
+	jsonQ, htmlQ := 0.0, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		params := strings.Split(part, ";")
+		typ := strings.ToLower(strings.TrimSpace(params[0]))
+
+		q := 1.0
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if k == "q" {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+
+		switch {
+		case typ == "application/json" || strings.HasSuffix(typ, "+json"):
+			jsonQ = max(jsonQ, q)
+		case typ == "text/html":
+			htmlQ = max(htmlQ, q)
+		}
+	}
+	return jsonQ > 0 && jsonQ >= htmlQ
+}
@@ -0,0 +1,93 @@
+package quincy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_JSONError(t *testing.T) {
+	w := httptest.NewRecorder()
+	JSONError(w, http.StatusNotFound, "account not found")
+
+	if w.Code != http.StatusNotFound {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Error("Invalid content type: ", ct)
+	}
+
+	var body struct {
+		Error  string
+		Status int
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal("invalid JSON body: ", err)
+	}
+	if body.Error != "account not found" || body.Status != 404 {
+		t.Errorf("invalid body: %+v", body)
+	}
+}
+
+func Test_JSONErrors(t *testing.T) {
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return Fail(c, http.StatusServiceUnavailable, errors.New("datastore unavailable"))
+	}
+
+	nextCalled := false
+	q := New(mw)
+	q.ContextFunc = background
+	q.OnError(JSONErrors(func(c context.Context, w http.ResponseWriter, r *http.Request, err error) {
+		nextCalled = true
+	}))
+	fn := q.Then(nil)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	fn(w, r)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if w.Body.String() != `{"error":"Service Unavailable","status":503}`+"\n" {
+		t.Error("Invalid response body: ", w.Body.String())
+	}
+	if nextCalled {
+		t.Error("next should not be called for JSON requests")
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", "text/html,application/json;q=0.9")
+	w = httptest.NewRecorder()
+	fn(w, r)
+
+	if !nextCalled {
+		t.Error("next should be called for HTML requests")
+	}
+	if w.Code != http.StatusServiceUnavailable || w.Body.Len() != 0 {
+		t.Error("Invalid fallback response: ", w.Code, w.Body.String())
+	}
+}
+
+func Test_JSONErrors_Started(t *testing.T) {
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return Abort(c, w, http.StatusForbidden)
+	}
+
+	q := New(mw)
+	q.ContextFunc = background
+	q.OnError(JSONErrors(nil))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	q.Then(nil)(w, r)
+
+	if w.Code != http.StatusForbidden || w.Body.Len() != 0 {
+		t.Error("started response should not be written to: ", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,117 @@
+package locale
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// Param is the query parameter, and cookie, that overrides the Accept-Language
+// header
+const Param = "lang"
+
+type locale string
+
+// Detect returns a middleware that selects the supported locale best matching the
+// Accept-Language header of the request, honoring the quality values, and stores
+// it on the context. A supported locale given by the lang query parameter or
+// cookie takes precedence, and the first supported locale is used when none match.
+//	q := quincy.New(locale.Detect("en", "fr", "de"))
+func Detect(supported ...string) quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		if len(supported) == 0 {
+			return c
+		}
+		return quincy.WithValue(c, locale(match(r, supported)))
+	}
+}
+
+// From returns the locale selected by Detect, or an empty string if none was
+// selected
+//	msg := translations[locale.From(c)]["welcome"]
+func From(c context.Context) string {
+	l, _ := quincy.Value[locale](c)
+	return string(l)
+}
+
+// returns the locale for the request, giving the override first preference
+func match(r *http.Request, supported []string) string {
+	override := r.URL.Query().Get(Param)
+	if override == "" {
+		if cookie, err := r.Cookie(Param); err == nil {
+			override = cookie.Value
+		}
+	}
+	for _, s := range supported {
+		if override != "" && strings.EqualFold(s, override) {
+			return s
+		}
+	}
+
+	ranges := parseAcceptLanguage(r.Header.Get("Accept-Language"))
+	best, bestQ := supported[0], 0.0
+	for _, s := range supported {
+		if q := quality(ranges, s); q > bestQ {
+			best, bestQ = s, q
+		}
+	}
+	return best
+}
+
+// languageRange is a single entry of an Accept-Language header
+type languageRange struct {
+	tag string
+	q   float64
+}
+
+// parses the language ranges of an Accept-Language header
+func parseAcceptLanguage(header string) []languageRange {
+	var ranges []languageRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(params[0]))
+		if tag == "" {
+			continue
+		}
+
+		lr := languageRange{tag: tag, q: 1}
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if k == "q" {
+				if q, err := strconv.ParseFloat(v, 64); err == nil {
+					lr.q = q
+				}
+			}
+		}
+		ranges = append(ranges, lr)
+	}
+	return ranges
+}
+
+// returns the quality of the locale given by the most specific language range
+// that matches it, or zero if none match. A range matches a locale with the same
+// tag, a locale it is a prefix of, such as en for en-US, or a locale that is a
+// prefix of it, such as fr for fr-CA.
+func quality(ranges []languageRange, l string) float64 {
+	l = strings.ToLower(l)
+
+	q, specificity := 0.0, -1
+	for _, lr := range ranges {
+		s := -1
+		switch {
+		case lr.tag == l:
+			s = 2
+		case strings.HasPrefix(l, lr.tag+"-"), strings.HasPrefix(lr.tag, l+"-"):
+			s = 1
+		case lr.tag == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = lr.q, s
+		}
+	}
+	return q
+}
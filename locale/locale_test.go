@@ -0,0 +1,55 @@
+package locale
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Detect(t *testing.T) {
+	tests := map[string]string{
+		"":                       "en",
+		"fr":                     "fr",
+		"de-DE,de;q=0.9":         "de",
+		"es, fr;q=0.5, de;q=0.8": "de",
+		"fr-CA, en;q=0.5":        "fr",
+		"ja":                     "en",
+		"*;q=0.1, fr;q=0":        "en",
+		"en;q=0.2, fr;q=0.1, DE": "de",
+	}
+
+	for header, expected := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Language", header)
+		c := Detect("en", "fr", "de")(context.Background(), nil, r)
+
+		if l := From(c); l != expected {
+			t.Errorf("%q: expected %s, got %s", header, expected, l)
+		}
+	}
+}
+
+func Test_Detect_Override(t *testing.T) {
+	r := httptest.NewRequest("GET", "/?lang=de", nil)
+	r.Header.Set("Accept-Language", "fr")
+	c := Detect("en", "fr", "de")(context.Background(), nil, r)
+	if From(c) != "de" {
+		t.Error("query parameter should override the header, got ", From(c))
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Language", "fr")
+	r.AddCookie(&http.Cookie{Name: Param, Value: "de"})
+	c = Detect("en", "fr", "de")(context.Background(), nil, r)
+	if From(c) != "de" {
+		t.Error("cookie should override the header, got ", From(c))
+	}
+
+	r = httptest.NewRequest("GET", "/?lang=ja", nil)
+	r.Header.Set("Accept-Language", "fr")
+	c = Detect("en", "fr", "de")(context.Background(), nil, r)
+	if From(c) != "fr" {
+		t.Error("unsupported override should be ignored, got ", From(c))
+	}
+}
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+	"google.golang.org/appengine/log"
+)
+
+// Entry holds the details of a request that are logged once it completes
+type Entry struct {
+	Method string
+	Path   string
+	// Route is the pattern of the route that matched the request, or its path if
+	// none was set
+	Route   string
+	Status  int
+	Latency time.Duration
+}
+
+// String formats the entry as the default log line
+func (e Entry) String() string {
+	return fmt.Sprintf("%s %s %d %v", e.Method, e.Path, e.Status, e.Latency)
+}
+
+type config struct {
+	w      io.Writer
+	format func(Entry) string
+}
+
+// Option configures the request logger
+type Option func(*config)
+
+// Writer writes the log lines to w rather than to the App Engine log
+func Writer(w io.Writer) Option {
+	return func(cfg *config) {
+		cfg.w = w
+	}
+}
+
+// Format formats each log line with fn rather than with Entry.String
+func Format(fn func(Entry) string) Option {
+	return func(cfg *config) {
+		cfg.format = fn
+	}
+}
+
+// Log returns a middleware that logs the method, path, status and latency of each
+// request once the final handler returns. The lines are written to the App Engine
+// log of the request context unless the Writer option is provided.
+//	q := quincy.New(logger.Log())
+func Log(opts ...Option) quincy.Middleware {
+	cfg := config{format: Entry.String}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		quincy.Defer(c, func() {
+			e := Entry{
+				Method:  r.Method,
+				Path:    r.URL.Path,
+				Route:   quincy.RoutePattern(c),
+				Status:  http.StatusOK,
+				Latency: quincy.Elapsed(c),
+			}
+			if rec, ok := w.(*quincy.StatusRecorder); ok && rec.Status() != 0 {
+				e.Status = rec.Status()
+			}
+
+			line := cfg.format(e)
+			if cfg.w != nil {
+				fmt.Fprintln(cfg.w, line)
+				return
+			}
+			log.Infof(c, "%s", line)
+		})
+		return c
+	}
+}
@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/testutil"
+)
+
+func Test_Log(t *testing.T) {
+	var buf bytes.Buffer
+	q := quincy.New(Log(Writer(&buf)))
+	q.ContextFunc = testutil.StubContext
+
+	notFound := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	ok := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	notFound(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+	ok(httptest.NewRecorder(), httptest.NewRequest("POST", "/accounts", nil))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Error("invalid number of log lines: ", len(lines))
+		return
+	}
+	if !strings.HasPrefix(lines[0], "GET /missing 404 ") {
+		t.Error("invalid log line: ", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "POST /accounts 200 ") {
+		t.Error("invalid log line: ", lines[1])
+	}
+}
@@ -0,0 +1,109 @@
+package maintenance
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/realip"
+)
+
+type config struct {
+	retryAfter time.Duration
+	body       string
+	paths      []string
+	ips        map[string]bool
+}
+
+// Option configures the maintenance middleware
+type Option func(*config)
+
+// RetryAfter sets how long clients are told to wait before retrying, which
+// defaults to 5 minutes
+func RetryAfter(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.retryAfter = d
+	}
+}
+
+// Body sets the plain text body of the response
+func Body(body string) Option {
+	return func(cfg *config) {
+		cfg.body = body
+	}
+}
+
+// AllowPaths lets requests for paths starting with any of the prefixes through,
+// such as health checks
+func AllowPaths(prefixes ...string) Option {
+	return func(cfg *config) {
+		cfg.paths = append(cfg.paths, prefixes...)
+	}
+}
+
+// AllowIPs lets requests from the IPs through, so operators can check the app
+// before it is brought back. The IP resolved by realip.Resolve is used when it is
+// in the chain, otherwise the IP of r.RemoteAddr.
+func AllowIPs(ips ...string) Option {
+	return func(cfg *config) {
+		for _, ip := range ips {
+			cfg.ips[ip] = true
+		}
+	}
+}
+
+// Mode returns a middleware that responds with a 503 and ends the chain while
+// enabled returns true. Since enabled is called for each request it should be
+// cheap, such as reading a value that is refreshed from memcache in the
+// background.
+//	q := quincy.New(maintenance.Mode(down.Load, maintenance.AllowPaths("/_ah/")))
+func Mode(enabled func() bool, opts ...Option) quincy.Middleware {
+	cfg := config{
+		retryAfter: 5 * time.Minute,
+		body:       "Down for maintenance, please try again shortly",
+		ips:        map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	retryAfter := strconv.Itoa(int(cfg.retryAfter / time.Second))
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		if !enabled() || allowed(c, r, &cfg) {
+			return c
+		}
+
+		h := w.Header()
+		h.Set("Retry-After", retryAfter)
+		h.Set("Content-Type", "text/plain; charset=utf-8")
+		h.Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(cfg.body))
+		return quincy.Stop(c)
+	}
+}
+
+// reports whether the request is let through despite maintenance
+func allowed(c context.Context, r *http.Request, cfg *config) bool {
+	for _, prefix := range cfg.paths {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+	if len(cfg.ips) == 0 {
+		return false
+	}
+
+	ip := realip.ClientIP(c)
+	if ip == "" {
+		ip = r.RemoteAddr
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	return cfg.ips[ip]
+}
@@ -0,0 +1,53 @@
+package maintenance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Mode(t *testing.T) {
+	enabled := false
+	mw := Mode(func() bool { return enabled })
+
+	w := httptest.NewRecorder()
+	mw(context.Background(), w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 200 {
+		t.Error("request should pass through while disabled")
+	}
+
+	enabled = true
+	w = httptest.NewRecorder()
+	mw(context.Background(), w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 503 || w.Header().Get("Retry-After") != "300" {
+		t.Error("invalid maintenance response: ", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func Test_Mode_Allowed(t *testing.T) {
+	mw := Mode(func() bool { return true }, AllowPaths("/_ah/"), AllowIPs("10.0.0.1"))
+
+	operator := httptest.NewRequest("GET", "/", nil)
+	operator.RemoteAddr = "10.0.0.1:1234"
+	noPort := httptest.NewRequest("GET", "/", nil)
+	noPort.RemoteAddr = "10.0.0.1"
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest("GET", "/_ah/health", nil),
+		operator,
+		noPort,
+	} {
+		w := httptest.NewRecorder()
+		mw(context.Background(), w, r)
+		if w.Code != 200 {
+			t.Error("request should be allowed: ", r.URL.Path, r.RemoteAddr)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	mw(context.Background(), w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 503 {
+		t.Error("expected 503, got ", w.Code)
+	}
+}
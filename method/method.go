@@ -0,0 +1,45 @@
+package method
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// Header is the request header carrying the overriding method
+const Header = "X-HTTP-Method-Override"
+
+// FormField is the form field carrying the overriding method
+const FormField = "_method"
+
+// the methods a POST request is allowed to be overridden with
+var allowed = map[string]bool{
+	"PUT":    true,
+	"PATCH":  true,
+	"DELETE": true,
+}
+
+// Override returns a middleware that rewrites the method of POST requests to the
+// method given by the X-HTTP-Method-Override header, or failing that the _method
+// form field, allowing HTML forms to make PUT, PATCH and DELETE requests. Any
+// other override, or request method, is left as is.
+//	q := quincy.New(method.Override())
+func Override() quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		if r.Method != "POST" {
+			return c
+		}
+
+		m := r.Header.Get(Header)
+		if m == "" {
+			m = r.FormValue(FormField)
+		}
+		m = strings.ToUpper(m)
+		if allowed[m] {
+			r.Method = m
+		}
+		return c
+	}
+}
@@ -0,0 +1,56 @@
+package method
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/testutil"
+)
+
+func serve(r *http.Request) string {
+	q := quincy.New(Override())
+	q.ContextFunc = testutil.StubContext
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method))
+	})
+
+	w := httptest.NewRecorder()
+	fn(w, r)
+	return w.Body.String()
+}
+
+func Test_OverrideHeader(t *testing.T) {
+	r := httptest.NewRequest("POST", "/accounts/1", nil)
+	r.Header.Set(Header, "DELETE")
+
+	if m := serve(r); m != "DELETE" {
+		t.Error("invalid method: ", m)
+	}
+}
+
+func Test_OverrideFormField(t *testing.T) {
+	r := httptest.NewRequest("POST", "/accounts/1", strings.NewReader("_method=put"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if m := serve(r); m != "PUT" {
+		t.Error("invalid method: ", m)
+	}
+}
+
+func Test_OverrideNotAllowed(t *testing.T) {
+	r := httptest.NewRequest("POST", "/accounts/1", nil)
+	r.Header.Set(Header, "CONNECT")
+	if m := serve(r); m != "POST" {
+		t.Error("invalid method: ", m)
+	}
+
+	r = httptest.NewRequest("GET", "/accounts/1", nil)
+	r.Header.Set(Header, "DELETE")
+	if m := serve(r); m != "GET" {
+		t.Error("non-POST requests should not be overridden: ", m)
+	}
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// Unlabelled is the route reported for requests that were not given a route with
+// SetRoute or a route pattern with quincy.WithRoutePattern, since reporting the
+// raw path would give each URL its own series
+const Unlabelled = "unlabelled"
+
+// Sink receives the metrics of each completed request
+type Sink interface {
+	IncRequest(method, route string, status int)
+	ObserveLatency(method, route string, status int, latency time.Duration)
+}
+
+// Nop is a Sink that discards the metrics
+var Nop Sink = nop{}
+
+type nop struct{}
+
+func (nop) IncRequest(string, string, int)                    {}
+func (nop) ObserveLatency(string, string, int, time.Duration) {}
+
+// route holds the route of the request, which is set by the middleware after
+// Record and read once the request completes
+type route struct {
+	name string
+}
+
+// Record returns a middleware that reports the request count and latency to the
+// sink once the request completes, labelled by method, route and status. The
+// route given to SetRoute is used over the route pattern set by the router. A nil
+// sink is replaced by Nop.
+//	q := quincy.New(metrics.Record(sink))
+func Record(sink Sink) quincy.Middleware {
+	if sink == nil {
+		sink = Nop
+	}
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		rt := &route{}
+		quincy.Defer(c, func() {
+			status := http.StatusOK
+			if rec, ok := w.(*quincy.StatusRecorder); ok && rec.Status() != 0 {
+				status = rec.Status()
+			}
+			name := rt.name
+			if name == "" {
+				name = Unlabelled
+				if pattern, ok := quincy.LookupRoutePattern(c); ok {
+					name = pattern
+				}
+			}
+			sink.IncRequest(r.Method, name, status)
+			sink.ObserveLatency(r.Method, name, status, quincy.Elapsed(c))
+		})
+		return quincy.WithValue(c, rt)
+	}
+}
+
+// SetRoute sets the route the request is reported under, which should be the
+// pattern that matched the request rather than its path
+//	metrics.SetRoute(c, "/users/:id")
+func SetRoute(c context.Context, name string) {
+	if rt, _ := quincy.Value[*route](c); rt != nil {
+		rt.name = name
+	}
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/testutil"
+)
+
+type sink struct {
+	requests  map[string]int
+	latencies int
+}
+
+func (s *sink) IncRequest(method, route string, status int) {
+	s.requests[method+" "+route+" "+http.StatusText(status)]++
+}
+
+func (s *sink) ObserveLatency(method, route string, status int, latency time.Duration) {
+	s.latencies++
+}
+
+func Test_Record(t *testing.T) {
+	s := &sink{requests: map[string]int{}}
+	q := quincy.New(Record(s))
+	q.ContextFunc = testutil.StubContext
+
+	user := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		SetRoute(c, "/users/:id")
+	})
+	missing := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	user(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/1", nil))
+	user(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/2", nil))
+	missing(httptest.NewRecorder(), httptest.NewRequest("POST", "/nope", nil))
+
+	if s.requests["GET /users/:id OK"] != 2 {
+		t.Error("invalid count for the labelled route: ", s.requests)
+	}
+	if s.requests["POST unlabelled Not Found"] != 1 {
+		t.Error("invalid count for the unlabelled route: ", s.requests)
+	}
+	if s.latencies != 3 {
+		t.Error("expected 3 latencies, got ", s.latencies)
+	}
+}
+
+func Test_RecordRoutePattern(t *testing.T) {
+	s := &sink{requests: map[string]int{}}
+	router := quincy.NewRouter(Record(s))
+	router.Chain().ContextFunc = testutil.StubContext
+	router.Get("/users/:id", func(c context.Context, w http.ResponseWriter, r *http.Request) {})
+	router.Get("/accounts/:id", func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		SetRoute(c, "accounts")
+	})
+
+	for _, path := range []string{"/users/1", "/users/2", "/accounts/3"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	if s.requests["GET /users/:id OK"] != 2 {
+		t.Error("requests should be labelled by the route pattern: ", s.requests)
+	}
+	if s.requests["GET accounts OK"] != 1 {
+		t.Error("SetRoute should be used over the route pattern: ", s.requests)
+	}
+}
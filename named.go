@@ -0,0 +1,78 @@
+package quincy
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+type nameKey struct{}
+
+// all middleware returned by Named share the same code pointer, which allows
+// them to be told apart from other middleware
+var namedPtr = reflect.ValueOf(Named("", nil)).Pointer()
+
+// Named attaches a name to the middleware, which is used by Q.String and reported
+// by AbortedIn when the middleware aborts the chain
+//	q := quincy.New(quincy.Named("auth", auth))
+func Named(name string, mw Middleware) Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		if p, ok := c.Value(nameKey{}).(*string); ok {
+			*p = name
+			return c
+		}
+		return mw(c, w, r)
+	}
+}
+
+// String lists the names of the middleware in the order they are run. Middleware
+// that were not wrapped with Named are listed by their function name.
+func (q *Q) String() string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return strings.Join(namesOf(q.fns), ", ")
+}
+
+// AbortedIn returns the name of the middleware that aborted the chain, or an empty
+// string if the chain was not aborted by a middleware. It is intended to be used
+// within the OnError hook.
+//	q.OnError(func(c context.Context, w http.ResponseWriter, r *http.Request, err error) {
+//		log.Warningf(c, "aborted in %s: %v", quincy.AbortedIn(c), err)
+//	})
+func AbortedIn(c context.Context) string {
+	st := stateFrom(c)
+	if st == nil || st.abortedAt < 0 || st.abortedAt >= len(st.names) {
+		return ""
+	}
+	return st.names[st.abortedAt]
+}
+
+// returns the names of the middleware, skipping the nil items as the chain does
+func namesOf(fns []Middleware) []string {
+	fns = compact(fns)
+	names := make([]string, len(fns))
+	for i, fn := range fns {
+		names[i] = nameOf(fn)
+	}
+	return names
+}
+
+// returns the name attached by Named, falling back to the function name with the
+// package path removed
+func nameOf(fn Middleware) string {
+	ptr := reflect.ValueOf(fn).Pointer()
+	if ptr == namedPtr {
+		var name string
+		fn(context.WithValue(context.Background(), nameKey{}, &name), nil, nil)
+		return name
+	}
+
+	f := runtime.FuncForPC(ptr)
+	if f == nil {
+		return "unknown"
+	}
+	name := f.Name()
+	return name[strings.LastIndex(name, "/")+1:]
+}
@@ -0,0 +1,47 @@
+package quincy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testMiddleware(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+	return c
+}
+
+func Test_String(t *testing.T) {
+	var calls []string
+	q := New(Named("logger", record("a", &calls)), Named("auth", record("b", &calls)), testMiddleware)
+
+	if q.String() != "logger, auth, quincy.testMiddleware" {
+		t.Error("invalid chain names: ", q.String())
+	}
+
+	q.Run(context.Background(), nil, nil)
+	if len(calls) != 2 {
+		t.Error("named middleware were not called")
+	}
+}
+
+func Test_AbortedIn(t *testing.T) {
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return Abort(c, w, http.StatusForbidden)
+	}
+
+	q := New(Named("logger", testMiddleware), Named("auth", mw), Named("session", testMiddleware))
+	q.ContextFunc = background
+
+	var name string
+	q.OnError(func(c context.Context, w http.ResponseWriter, r *http.Request, err error) {
+		name = AbortedIn(c)
+	})
+
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {})
+	fn(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if name != "auth" {
+		t.Error("invalid aborting middleware name: ", name)
+	}
+}
@@ -0,0 +1,31 @@
+package quincy
+
+import "context"
+
+// Params holds the url params of a request, keyed by the param name
+type Params map[string]string
+
+// Get returns the value of the named param, or an empty string if it is not set
+func (p Params) Get(key string) string {
+	return p[key]
+}
+
+type paramsKey struct{}
+
+// WithParams returns a context that carries the url params. Router sets the params
+// of the route it matched, while a middleware adapting another router is expected
+// to set them for the handlers to read.
+//	func routerParams(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+//		return quincy.WithParams(c, quincy.Params(mux.Vars(r)))
+//	}
+func WithParams(c context.Context, p Params) context.Context {
+	return context.WithValue(c, paramsKey{}, p)
+}
+
+// ParamsFrom returns the url params carried by the context, which is nil if no
+// params were set
+//	id := quincy.ParamsFrom(c).Get("id")
+func ParamsFrom(c context.Context) Params {
+	p, _ := c.Value(paramsKey{}).(Params)
+	return p
+}
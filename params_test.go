@@ -0,0 +1,24 @@
+package quincy
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_Params(t *testing.T) {
+	c := WithParams(context.Background(), Params{"id": "123"})
+
+	p := ParamsFrom(c)
+	if p.Get("id") != "123" {
+		t.Error("param value does not match the expected")
+	}
+	if p.Get("name") != "" {
+		t.Error("missing param should be empty")
+	}
+}
+
+func Test_ParamsMissing(t *testing.T) {
+	if ParamsFrom(context.Background()).Get("id") != "" {
+		t.Error("missing params should be empty")
+	}
+}
@@ -1,9 +1,14 @@
 package quincy
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
 
-	"golang.org/x/net/context"
 	"google.golang.org/appengine"
 )
 
@@ -13,38 +18,185 @@ type Middleware func(context.Context, http.ResponseWriter, *http.Request) contex
 // HandlerFunc much like the standard http.HandlerFunc, but includes the request context
 type HandlerFunc func(context.Context, http.ResponseWriter, *http.Request)
 
+// ServeHTTP calls f(c, w, r), allowing a HandlerFunc to be used as a Handler
+func (f HandlerFunc) ServeHTTP(c context.Context, w http.ResponseWriter, r *http.Request) {
+	f(c, w, r)
+}
+
 // Handler much like the standard http.Handler, but includes the request context
 // in the ServeHTTP method
 type Handler interface {
 	ServeHTTP(context.Context, http.ResponseWriter, *http.Request)
 }
 
+// ErrorFunc is called with the cause of the context error when a middleware aborts
+// the chain
+type ErrorFunc func(context.Context, http.ResponseWriter, *http.Request, error)
+
 // handler allows the middleware calls to be wrapped up into a Handler interface
 type handler struct {
-	mw      Middleware
-	handler Handler
+	ctxFn         func(*http.Request) context.Context
+	timeoutStatus int
+	mw            Middleware
+	onError       ErrorFunc
+	fn            HandlerFunc
+	finally       []Middleware
+	names         []string
 }
 
 func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	c := appengine.NewContext(r)
+	rec := NewStatusRecorder(w)
+	w = rec
+
+	st := &state{names: h.names, abortedAt: -1, timeoutStatus: h.timeoutStatus, path: r.URL.Path, start: time.Now()}
+	c := context.WithValue(h.ctxFn(r), stateKey{}, st)
+	completed := false
+	defer func() {
+		if st.recover {
+			if v := recover(); v != nil {
+				err := &PanicError{Value: v, Stack: debug.Stack()}
+				st.err = err
+				if h.onError != nil {
+					h.onError(c, w, r, err)
+				}
+				if rec.Status() == 0 {
+					if st.showStack {
+						http.Error(w, err.Error()+"\n\n"+string(err.Stack), http.StatusInternalServerError)
+					} else {
+						w.WriteHeader(http.StatusInternalServerError)
+					}
+				}
+			}
+		}
+		if !completed && st.err == nil {
+			st.err = ErrPanic
+		}
+		for i := len(st.deferred) - 1; i >= 0; i-- {
+			st.deferred[i]()
+		}
+		for _, fn := range h.finally {
+			c = fn(c, w, r)
+		}
+	}()
+
 	c = h.mw(c, w, r)
-	if c.Err() == nil {
-		h.handler.ServeHTTP(c, w, r)
+	if c.Err() != nil {
+		completed = true
+		err := context.Cause(c)
+		st.err = err
+		if h.onError != nil {
+			h.onError(c, w, r, err)
+		}
+		// without a status the response would otherwise default to a 200, though
+		// nothing is written for a client that has gone away
+		if status := statusOf(c, err); rec.Status() == 0 && status != StatusClientClosedRequest {
+			w.WriteHeader(status)
+		}
+		return
+	}
+	if stopped(c) {
+		completed = true
+		return
+	}
+	h.fn(c, writerFrom(c, w), r)
+	completed = true
+}
+
+// state is created for each request handled by Then or Handle and allows the
+// built-in middleware to alter how the request is handled
+type state struct {
+	recover       bool
+	showStack     bool
+	names         []string
+	abortedAt     int
+	deferred      []func()
+	err           error
+	timeoutStatus int
+	path          string
+	pattern       string
+	start         time.Time
+}
+
+type stateKey struct{}
+
+// returns the request state, which is nil when the chain is not being run by
+// Then or Handle
+func stateFrom(c context.Context) *state {
+	st, _ := c.Value(stateKey{}).(*state)
+	return st
+}
+
+// Defer registers a function to be called once the final handler of the request
+// returns, or the chain is aborted, which allows a middleware to clean up or to
+// act on the outcome of the request. Deferred functions are called in the reverse
+// order they were registered, ahead of the Finally middleware. Defer returns false,
+// and fn is never called, if the chain is not being run by Then or Handle.
+//	start := time.Now()
+//	quincy.Defer(c, func() {
+//		log.Infof(c, "%s took %v", r.URL.Path, time.Since(start))
+//	})
+func Defer(c context.Context, fn func()) bool {
+	st := stateFrom(c)
+	if st == nil {
+		return false
 	}
+	st.deferred = append(st.deferred, fn)
+	return true
 }
 
-// Q allows a list middleware functions to be created and run
+// ErrPanic is the error reported by Err when the request panicked without Recover
+// in the chain to recover it
+var ErrPanic = errors.New("quincy: request panicked")
+
+// Err returns the error that ended the request, being the cause of an aborted
+// chain or the PanicError of a recovered panic, or nil if the request completed.
+// It is only set once the chain has run, so it is intended to be used within
+// deferred functions and Finally middleware to act on the outcome of the request.
+//	quincy.Defer(c, func() {
+//		if quincy.Err(c) != nil {
+//			rollback()
+//		}
+//	})
+func Err(c context.Context) error {
+	if st := stateFrom(c); st != nil {
+		return st.err
+	}
+	return nil
+}
+
+// Q allows a list middleware functions to be created and run. Its methods are safe
+// for concurrent use, with the exception of setting its exported fields.
 type Q struct {
-	fns []Middleware
+	mu      sync.Mutex
+	fns     []Middleware
+	finally []Middleware
+	onError ErrorFunc
+	def     HandlerFunc
+
+	// compiled caches the composed chain, which is cleared whenever the chain is
+	// modified and rebuilt on its next use
+	compiled *handler
+
+	// TimeoutStatus is written when the chain is stopped by its context deadline
+	// expiring and nothing else was written, defaulting to 504 when zero. A 408 may
+	// be used instead where the deadline reflects the client's own timeout.
+	TimeoutStatus int
+
+	// ContextFunc creates the root context for each request handled by Then and
+	// Handle. It defaults to appengine.NewContext, but can be replaced to run the
+	// chain outside of classic App Engine or to inject a context within tests.
+	ContextFunc func(*http.Request) context.Context
 }
 
 // New initializes the middleware chain with one or more handler functions.
 // The returned pointer allows for additional middleware methods to be added or
-// for the chain to be run.
+// for the chain to be run. The handler functions are copied, so later changes to
+// a slice passed in do not affect the chain.
 //	q := que.New(foo, bar)
 func New(fns ...Middleware) *Q {
 	q := Q{}
-	q.fns = fns
+	q.fns = append([]Middleware(nil), fns...)
+	q.ContextFunc = appengine.NewContext
 	return &q
 }
 
@@ -53,10 +205,138 @@ func New(fns ...Middleware) *Q {
 //	q := que.New(cors, format)
 //	q.Add(auth)
 func (q *Q) Add(fns ...Middleware) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 	q.fns = append(q.fns, fns...)
+	q.compiled = nil
+}
+
+// Use adds the middleware to the end of the chain, like Add, returning the chain
+// to allow calls to be chained
+//	q := quincy.New().Use(cors).Use(auth)
+func (q *Q) Use(fns ...Middleware) *Q {
+	q.Add(fns...)
+	return q
+}
+
+// Finally adds one or more middleware handler functions that are run, in order,
+// once the final handler returns within Then or Handle. They are run even when
+// the chain was aborted, in which case the context they receive is the one that
+// stopped the chain, making them suited for cleanup and for recording the
+// outcome of the request.
+//	q := quincy.New(auth)
+//	q.Finally(logRequest)
+func (q *Q) Finally(fns ...Middleware) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.finally = append(q.finally, fns...)
+	q.compiled = nil
+}
+
+// Insert places one or more middleware handler functions into the existing chain
+// at the index provided, shifting the middleware at and after that index back.
+// An index equal to the chain length appends the functions, while a negative
+// index or one past the end of the chain panics.
+//	q := quincy.New(logger, auth)
+//	q.Insert(1, session)
+func (q *Q) Insert(index int, fns ...Middleware) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if index < 0 || index > len(q.fns) {
+		panic(fmt.Sprintf("quincy: insert index %d out of range [0:%d]", index, len(q.fns)))
+	}
+	list := make([]Middleware, 0, len(q.fns)+len(fns))
+	list = append(list, q.fns[:index]...)
+	list = append(list, fns...)
+	q.fns = append(list, q.fns[index:]...)
+	q.compiled = nil
+}
+
+// Prepend places one or more middleware handler functions, in order, ahead of
+// the existing chain
+//	q := quincy.New(logger, auth)
+//	q.Prepend(requestID)
+func (q *Q) Prepend(fns ...Middleware) {
+	q.Insert(0, fns...)
+}
+
+// RemoveAt removes the middleware handler function at the index provided from
+// the existing chain, returning an error if the index is out of range
+//	q := quincy.New(logger, rateLimit, auth)
+//	err := q.RemoveAt(1)
+func (q *Q) RemoveAt(index int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if index < 0 || index >= len(q.fns) {
+		return fmt.Errorf("quincy: remove index %d out of range [0:%d]", index, len(q.fns))
+	}
+	list := make([]Middleware, 0, len(q.fns)-1)
+	list = append(list, q.fns[:index]...)
+	q.fns = append(list, q.fns[index+1:]...)
+	q.compiled = nil
+	return nil
+}
+
+// Len returns the number of middleware handler functions within the chain
+func (q *Q) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.fns)
+}
+
+// Middlewares returns a copy of the middleware handler functions within the chain,
+// which can be modified without affecting the chain
+func (q *Q) Middlewares() []Middleware {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	fns := make([]Middleware, len(q.fns))
+	copy(fns, q.fns)
+	return fns
+}
+
+// Clone returns a copy of the chain that can be customized without affecting
+// the original
+//	base := quincy.New(logger, auth)
+//	admin := base.Clone()
+//	admin.Add(requireAdmin)
+func (q *Q) Clone() *Q {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return &Q{
+		fns:           append([]Middleware(nil), q.fns...),
+		finally:       append([]Middleware(nil), q.finally...),
+		onError:       q.onError,
+		def:           q.def,
+		ContextFunc:   q.ContextFunc,
+		TimeoutStatus: q.TimeoutStatus,
+	}
+}
+
+// Merge returns a new chain made up of the middleware of the receiver followed
+// by the middleware of the other chain. Neither chain is modified.
+//	q := logging.Merge(auth)
+func (q *Q) Merge(other *Q) *Q {
+	m := q.Clone()
+	o := other.Clone()
+	m.fns = append(m.fns, o.fns...)
+	m.finally = append(m.finally, o.finally...)
+	m.compiled = nil
+	return m
+}
+
+// Group returns a clone of the chain with the extra middleware appended, allowing
+// a set of routes to share a base chain without affecting it
+//	api := base.Group(auth)
+//	router.Get("/accounts", api.Then(handleAccounts))
+func (q *Q) Group(extra ...Middleware) *Q {
+	g := q.Clone()
+	g.Add(extra...)
+	return g
 }
 
-// Run executes the handler chain, which is most useful in tests
+// Run executes the handler chain and returns the resulting context, which is most
+// useful in tests to assert on values set by the middleware or to check c.Err()
+// to verify that the chain was aborted
 //	q := que.New(foo, bar)
 // 	q.Add(func(c context.Context, w http.ResponseWriter, r *http.Request) {
 // 		// perform tests here
@@ -65,66 +345,183 @@ func (q *Q) Add(fns ...Middleware) {
 // 	r := inst.NewRequest("GET", "/", nil)
 // 	w := httpTest.NewRecorder()
 // 	c := appengine.NewContext(r)
-// 	q.Run(c, w, r)
-func (q *Q) Run(c context.Context, w http.ResponseWriter, r *http.Request) {
-	chain(q.fns)(c, w, r)
+// 	c = q.Run(c, w, r)
+func (q *Q) Run(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+	return q.chain()(c, w, r)
+}
+
+// RunE executes the handler chain like Run, but returns the error that aborted
+// the chain, or nil if it ran to completion
+//	if err := q.RunE(c, w, r); err != nil {
+//		t.Error("chain aborted: ", err)
+//	}
+func (q *Q) RunE(c context.Context, w http.ResponseWriter, r *http.Request) error {
+	c = q.Run(c, w, r)
+	if c.Err() != nil {
+		return context.Cause(c)
+	}
+	return nil
 }
 
 // Then returns the chain of existing middleware that includes the final HandlerFunc argument.
+// Middleware added to the chain afterwards are not included in the returned function.
 //	q := que.New(foo, bar)
 //  router.Get("/", q.Then(handleRoot))
 func (q *Q) Then(fn HandlerFunc) func(http.ResponseWriter, *http.Request) {
-	chn := chain(q.fns)
-
-	return func(w http.ResponseWriter, r *http.Request) {
-		c := appengine.NewContext(r)
-		c = chn(c, w, r)
+	return q.handler(fn).ServeHTTP
+}
 
-		if c.Err() == nil {
-			fn(c, w, r)
-		}
+// ThenWith returns the chain ending with fn like Then, but creates the root context
+// of each request with ctxFn rather than the ContextFunc of the chain. This allows
+// routes sharing a chain to start from different contexts. A nil ctxFn falls back
+// to ContextFunc.
+//	router.Get("/", q.ThenWith(func(r *http.Request) context.Context {
+//		return quincy.WithValue(r.Context(), tenant)
+//	}, handleRoot))
+func (q *Q) ThenWith(ctxFn func(*http.Request) context.Context, fn HandlerFunc) http.HandlerFunc {
+	h := q.handler(fn)
+	if ctxFn != nil {
+		h.ctxFn = ctxFn
 	}
+	return h.ServeHTTP
 }
 
 // Handle accepts a Handler interface and returns the chain of existing middleware
-// that includes the final Handler argument.
+// that includes the final Handler argument. Middleware added to the chain afterwards
+// are not included in the returned handler.
 //	q := que.New(foo, bar)
 //  router.Get("/", q.Then(handleRoot))
 func (q *Q) Handle(h Handler) http.Handler {
-	mw := chain(q.fns)
-	return handler{mw: mw, handler: h}
+	return q.handler(h.ServeHTTP)
 }
 
-// converts the middleware slice into a series of middleware functions and returns
-// a reference to the first middleware item in the chain
-func chain(fns []Middleware) Middleware {
-	var next Middleware
-	var count = len(fns)
-	for i := count - 1; i >= 0; i-- {
-		next = link(fns[i], next)
+// Mount registers the chain, ending with fn, on the mux under the pattern
+//	mux := http.NewServeMux()
+//	api.Mount(mux, "/api/accounts", handleAccounts)
+func (q *Q) Mount(mux *http.ServeMux, pattern string, fn HandlerFunc) {
+	mux.HandleFunc(pattern, q.Then(fn))
+}
+
+// MountHandler registers the chain, ending with h, on the mux under the pattern
+//	api.MountHandler(mux, "/api/reports/", reports)
+func (q *Q) MountHandler(mux *http.ServeMux, pattern string, h Handler) {
+	mux.Handle(pattern, q.Handle(h))
+}
+
+// Default sets the final handler that is called when the chain itself is used as
+// a http.Handler
+//	q := quincy.New(foo, bar)
+//	q.Default(handleRoot)
+//	http.Handle("/", q)
+func (q *Q) Default(fn HandlerFunc) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.def = fn
+	q.compiled = nil
+}
+
+// ServeHTTP runs the chain followed by the default handler, allowing the chain to
+// be used as a http.Handler. A 404 is written if no default handler is set.
+func (q *Q) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q.mu.Lock()
+	def := q.def
+	q.mu.Unlock()
+
+	if def == nil {
+		http.Error(w, "quincy: no default handler set", http.StatusNotFound)
+		return
 	}
+	q.handler(def).ServeHTTP(w, r)
+}
+
+// OnError sets the function that is called once for each request in which a
+// middleware aborts the chain. If neither the aborting middleware nor the hook
+// writes a response, the status given to Fail, or otherwise a 500, is written.
+//	q := quincy.New(auth)
+//	q.OnError(func(c context.Context, w http.ResponseWriter, r *http.Request, err error) {
+//		log.Errorf(c, "request aborted: %v", err)
+//	})
+func (q *Q) OnError(fn ErrorFunc) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.onError = fn
+	q.compiled = nil
+}
+
+// composes the existing middleware and the final handler function. The handler
+// holds a snapshot of the chain, so changes made to the chain afterwards don't
+// affect it.
+func (q *Q) handler(fn HandlerFunc) handler {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	h := *q.compile()
+	h.ctxFn = q.contextFunc()
+	h.timeoutStatus = q.TimeoutStatus
+	h.fn = fn
+	return h
+}
+
+// returns the composed middleware of the chain
+func (q *Q) chain() Middleware {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.compile().mw
+}
 
-	// if there is no middleware a non-nil function is required to allow the final
-	// handler function to be called
-	if count == 0 {
-		next = func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
-			return c
+// returns the cached composition of the chain, rebuilding it if the chain was
+// modified since it was last composed. It must be called with the lock held.
+func (q *Q) compile() *handler {
+	if q.compiled == nil {
+		q.compiled = &handler{
+			mw:      chain(q.fns),
+			onError: q.onError,
+			finally: append([]Middleware(nil), q.finally...),
+			names:   namesOf(q.fns),
 		}
 	}
+	return q.compiled
+}
 
-	return next
+// returns the function used to create the root context, falling back to the
+// App Engine context for a Q that was not created with New
+func (q *Q) contextFunc() func(*http.Request) context.Context {
+	if q.ContextFunc != nil {
+		return q.ContextFunc
+	}
+	return appengine.NewContext
 }
 
-// links the two middleware functions to allow the first to call the next on completion
-func link(current, next Middleware) Middleware {
+// converts the middleware slice into a single middleware function that runs each
+// item in turn, stopping at the first that aborts or stops the chain. Nil items
+// are skipped to allow for middleware that are conditionally created.
+func chain(fns []Middleware) Middleware {
+	fns = compact(fns)
+
 	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
-		c = current(c, w, r)
-		if c.Err() != nil {
-			return c
-		}
-		if next != nil {
-			c = next(c, w, r)
+		for i, fn := range fns {
+			c = fn(c, w, r)
+			w = writerFrom(c, w)
+			if c.Err() != nil {
+				if st := stateFrom(c); st != nil {
+					st.abortedAt = i
+				}
+				return c
+			}
+			if stopped(c) {
+				return c
+			}
 		}
 		return c
 	}
 }
+
+// returns a copy of the middleware slice without the nil items
+func compact(fns []Middleware) []Middleware {
+	list := make([]Middleware, 0, len(fns))
+	for _, fn := range fns {
+		if fn != nil {
+			list = append(list, fn)
+		}
+	}
+	return list
+}
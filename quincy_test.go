@@ -1,12 +1,29 @@
 package quincy
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
-
-	"golang.org/x/net/context"
+	"time"
 )
 
+// creates the root context for chains run by Then or Handle within tests
+func background(r *http.Request) context.Context {
+	return context.Background()
+}
+
+// returns a middleware that appends its name to the list of called names
+func record(name string, calls *[]string) Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		*calls = append(*calls, name)
+		return c
+	}
+}
+
 func Test_Context(t *testing.T) {
 	mw1 := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
 		return context.WithValue(c, "key", "foobar")
@@ -60,3 +77,609 @@ func Test_NoMiddleware(t *testing.T) {
 	})
 	q.Run(c, nil, nil)
 }
+
+func Test_ContextFunc(t *testing.T) {
+	q := New()
+	q.ContextFunc = func(r *http.Request) context.Context {
+		return context.WithValue(context.Background(), "key", "foobar")
+	}
+
+	var called bool
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		called = true
+		if c.Value("key") != "foobar" {
+			t.Error("context was not created by the ContextFunc")
+		}
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	fn(httptest.NewRecorder(), r)
+
+	if !called {
+		t.Error("handler was not called")
+	}
+}
+
+func Test_StdlibDeadline(t *testing.T) {
+	deadline := time.Now().Add(time.Minute)
+	c, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		d, ok := c.Deadline()
+		if !ok {
+			t.Error("deadline missing within the middleware")
+		}
+		if !d.Equal(deadline) {
+			t.Error("deadline does not match the expected")
+		}
+		return c
+	}
+
+	New(mw, mw).Run(c, nil, nil)
+}
+
+func Test_RunReturnsContext(t *testing.T) {
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return context.WithValue(c, "key", "foobar")
+	}
+
+	c := New(mw).Run(context.Background(), nil, nil)
+	if c.Value("key") != "foobar" {
+		t.Error("returned context is missing the middleware value")
+	}
+}
+
+func Test_OnError(t *testing.T) {
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		c, cancel := context.WithCancel(c)
+		cancel()
+		return c
+	}
+
+	q := New(mw, mw)
+	q.ContextFunc = background
+
+	var calls int
+	q.OnError(func(c context.Context, w http.ResponseWriter, r *http.Request, err error) {
+		calls++
+		if err != context.Canceled {
+			t.Error("unexpected error: ", err)
+		}
+	})
+
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called")
+	})
+	fn(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if calls != 1 {
+		t.Error("error hook called an unexpected number of times: ", calls)
+	}
+}
+
+func Test_Insert(t *testing.T) {
+	var calls []string
+	q := New(record("a", &calls), record("c", &calls))
+	q.Insert(1, record("b", &calls))
+	q.Insert(3, record("d", &calls))
+	q.Run(context.Background(), nil, nil)
+
+	if strings.Join(calls, ",") != "a,b,c,d" {
+		t.Error("invalid middleware order: ", calls)
+	}
+}
+
+func Test_InsertOutOfRange(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected an out of range insert to panic")
+		}
+	}()
+
+	q := New()
+	q.Insert(1, record("a", &[]string{}))
+}
+
+func Test_Prepend(t *testing.T) {
+	var calls []string
+	q := New(record("c", &calls), record("d", &calls))
+	q.Prepend(record("a", &calls), record("b", &calls))
+	q.Run(context.Background(), nil, nil)
+
+	if strings.Join(calls, ",") != "a,b,c,d" {
+		t.Error("invalid middleware order: ", calls)
+	}
+}
+
+func Test_RemoveAt(t *testing.T) {
+	var calls []string
+	q := New(record("a", &calls), record("b", &calls), record("c", &calls))
+	if err := q.RemoveAt(1); err != nil {
+		t.Error("unexpected error: ", err)
+		return
+	}
+	q.Run(context.Background(), nil, nil)
+
+	if strings.Join(calls, ",") != "a,c" {
+		t.Error("invalid middleware order: ", calls)
+	}
+
+	if err := q.RemoveAt(2); err == nil {
+		t.Error("expected an error for an out of range index")
+	}
+}
+
+func Test_Len(t *testing.T) {
+	q := New()
+	if q.Len() != 0 {
+		t.Error("invalid length: ", q.Len())
+	}
+
+	q.Add(record("a", &[]string{}))
+	q.Add(record("b", &[]string{}), record("c", &[]string{}))
+	if q.Len() != 3 {
+		t.Error("invalid length: ", q.Len())
+	}
+}
+
+func Test_Clone(t *testing.T) {
+	var calls []string
+	q := New(record("a", &calls), record("b", &calls))
+	clone := q.Clone()
+	clone.Add(record("c", &calls))
+
+	if q.Len() != 2 {
+		t.Error("original chain was modified: ", q.Len())
+	}
+	if clone.Len() != 3 {
+		t.Error("invalid clone length: ", clone.Len())
+	}
+
+	clone.Run(context.Background(), nil, nil)
+	if strings.Join(calls, ",") != "a,b,c" {
+		t.Error("invalid middleware order: ", calls)
+	}
+}
+
+func Test_Merge(t *testing.T) {
+	var calls []string
+	q1 := New(record("a", &calls), record("b", &calls))
+	q2 := New(record("c", &calls))
+	m := q1.Merge(q2)
+
+	if q1.Len() != 2 || q2.Len() != 1 {
+		t.Error("merged chains were modified")
+	}
+
+	m.Run(context.Background(), nil, nil)
+	if strings.Join(calls, ",") != "a,b,c" {
+		t.Error("invalid middleware order: ", calls)
+	}
+}
+
+func Test_Group(t *testing.T) {
+	var calls []string
+	base := New(record("base", &calls))
+	api := base.Group(record("auth", &calls))
+	admin := base.Group(record("admin", &calls))
+
+	if base.Len() != 1 {
+		t.Error("base chain was modified: ", base.Len())
+	}
+
+	api.Run(context.Background(), nil, nil)
+	admin.Run(context.Background(), nil, nil)
+	if strings.Join(calls, ",") != "base,auth,base,admin" {
+		t.Error("invalid middleware order: ", calls)
+	}
+}
+
+func Test_Finally(t *testing.T) {
+	var status int
+	rec := httptest.NewRecorder()
+	q := New()
+	q.ContextFunc = background
+	q.Finally(func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		status = rec.Code
+		return c
+	})
+
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	fn(rec, httptest.NewRequest("GET", "/", nil))
+
+	if status != http.StatusAccepted {
+		t.Error("final status not recorded: ", status)
+	}
+}
+
+func Test_FinallyAborted(t *testing.T) {
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return Abort(c, w, http.StatusForbidden)
+	}
+
+	var called bool
+	q := New(mw)
+	q.ContextFunc = background
+	q.Finally(func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		called = true
+		if c.Err() == nil {
+			t.Error("expected the context of the aborted chain")
+		}
+		return c
+	})
+
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called")
+	})
+	fn(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if !called {
+		t.Error("finally middleware was not called")
+	}
+}
+
+func Test_ServeHTTP(t *testing.T) {
+	var calls []string
+	q := New(record("a", &calls))
+	q.ContextFunc = background
+	q.Default(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "default")
+	})
+
+	var h http.Handler = q
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusOK {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if strings.Join(calls, ",") != "a,default" {
+		t.Error("invalid call order: ", calls)
+	}
+}
+
+func Test_ServeHTTPWithoutDefault(t *testing.T) {
+	q := New()
+	q.ContextFunc = background
+
+	w := httptest.NewRecorder()
+	q.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Error("Invalid response status: ", w.Code)
+	}
+}
+
+func Test_Middlewares(t *testing.T) {
+	var calls []string
+	q := New(record("a", &calls), record("b", &calls), record("c", &calls))
+
+	fns := q.Middlewares()
+	if len(fns) != 3 {
+		t.Error("invalid middleware count: ", len(fns))
+		return
+	}
+
+	fns[0] = record("x", &calls)
+	q.Run(context.Background(), nil, nil)
+	if strings.Join(calls, ",") != "a,b,c" {
+		t.Error("chain was modified through the returned slice: ", calls)
+	}
+}
+
+func Test_RunE(t *testing.T) {
+	if err := New(testMiddleware).RunE(context.Background(), nil, nil); err != nil {
+		t.Error("unexpected error: ", err)
+	}
+
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return Abort(c, w, http.StatusForbidden)
+	}
+	if err := New(mw).RunE(context.Background(), httptest.NewRecorder(), nil); err == nil {
+		t.Error("expected an error from the aborted chain")
+	}
+}
+
+func Test_NewCopiesMiddleware(t *testing.T) {
+	var calls []string
+	fns := make([]Middleware, 2, 4)
+	fns[0] = record("a", &calls)
+	fns[1] = record("b", &calls)
+
+	q1 := New(fns...)
+	q2 := New(fns...)
+	fns[0] = record("x", &calls)
+	q1.Add(record("c", &calls))
+	q2.Add(record("d", &calls))
+
+	q1.Run(context.Background(), nil, nil)
+	if strings.Join(calls, ",") != "a,b,c" {
+		t.Error("chain affected by the source slice: ", calls)
+	}
+}
+
+func Test_ConcurrentAdd(t *testing.T) {
+	q := New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.Add(testMiddleware)
+			q.Clone()
+			q.Then(nil)
+		}()
+	}
+	wg.Wait()
+
+	if q.Len() != 50 {
+		t.Error("invalid length: ", q.Len())
+	}
+}
+
+func Test_AddAfterThen(t *testing.T) {
+	var calls []string
+	q := New(record("a", &calls))
+	q.ContextFunc = background
+	q.Default(func(c context.Context, w http.ResponseWriter, r *http.Request) {})
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {})
+
+	q.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	q.Add(record("b", &calls))
+
+	// handlers returned by Then keep the chain as it was when they were created,
+	// while the chain itself is rebuilt on its next use
+	fn(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	q.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if strings.Join(calls, ",") != "a,a,a,b" {
+		t.Error("invalid middleware order: ", calls)
+	}
+}
+
+func Test_ChainOrderAndShortCircuit(t *testing.T) {
+	var calls []string
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		calls = append(calls, "abort")
+		return abort(c)
+	}
+
+	fns := []Middleware{record("a", &calls), record("b", &calls), mw, record("c", &calls)}
+	c := chain(fns)(context.Background(), nil, nil)
+
+	if c.Err() == nil {
+		t.Error("chain should be aborted")
+	}
+	if strings.Join(calls, ",") != "a,b,abort" {
+		t.Error("invalid middleware order: ", calls)
+	}
+}
+
+func Test_NilMiddleware(t *testing.T) {
+	var calls []string
+	q := New(nil, record("a", &calls), nil, record("b", &calls), nil)
+	q.ContextFunc = background
+
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	})
+	fn(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if strings.Join(calls, ",") != "a,b,handler" {
+		t.Error("invalid call order: ", calls)
+	}
+}
+
+func Test_Defer(t *testing.T) {
+	var calls []string
+	mw := func(name string) Middleware {
+		return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+			if !Defer(c, func() { calls = append(calls, name) }) {
+				t.Error("function was not deferred")
+			}
+			return c
+		}
+	}
+
+	q := New(mw("a"), mw("b"))
+	q.ContextFunc = background
+	q.Finally(record("finally", &calls))
+
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	})
+	fn(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if strings.Join(calls, ",") != "handler,b,a,finally" {
+		t.Error("invalid call order: ", calls)
+	}
+
+	if Defer(context.Background(), func() {}) {
+		t.Error("functions should not be deferred outside of Then or Handle")
+	}
+}
+
+func Test_Use(t *testing.T) {
+	var calls []string
+	q := New(record("a", &calls))
+	q.ContextFunc = background
+
+	if q.Use(record("b", &calls)).Use(record("c", &calls)) != q {
+		t.Error("Use should return the same chain")
+	}
+	q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	})(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if strings.Join(calls, ",") != "a,b,c,handler" {
+		t.Error("invalid calls: ", calls)
+	}
+}
+
+func Test_ThenWith(t *testing.T) {
+	q := New(func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		if token, _ := Value[testToken](c); token != "foobar" {
+			t.Error("middleware should receive the provided context")
+		}
+		return c
+	})
+	q.ContextFunc = func(r *http.Request) context.Context {
+		t.Error("ContextFunc should not be called")
+		return context.Background()
+	}
+
+	var token testToken
+	fn := q.ThenWith(func(r *http.Request) context.Context {
+		return WithValue(context.Background(), testToken("foobar"))
+	}, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		token, _ = Value[testToken](c)
+	})
+	fn(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if token != "foobar" {
+		t.Error("handler should receive the provided context")
+	}
+}
+
+func Test_Mount(t *testing.T) {
+	var calls []string
+	q := New(record("a", &calls))
+	q.ContextFunc = background
+
+	mux := http.NewServeMux()
+	q.Mount(mux, "/accounts", func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "accounts")
+	})
+	q.MountHandler(mux, "/reports/", testHandler{&calls})
+
+	for _, path := range []string{"/accounts", "/reports/daily"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/other", nil))
+
+	if strings.Join(calls, ",") != "a,accounts,a,reports" {
+		t.Error("invalid call order: ", calls)
+	}
+	if w.Code != http.StatusNotFound {
+		t.Error("unmatched path should be handled by the mux: ", w.Code)
+	}
+}
+
+func Test_HandleHandlerFunc(t *testing.T) {
+	var calls []string
+	q := New(record("a", &calls), record("b", &calls))
+	q.ContextFunc = background
+
+	fn := HandlerFunc(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	})
+	q.Handle(fn).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if strings.Join(calls, ",") != "a,b,handler" {
+		t.Error("invalid calls: ", calls)
+	}
+}
+
+type testHandler struct {
+	calls *[]string
+}
+
+func (h testHandler) ServeHTTP(c context.Context, w http.ResponseWriter, r *http.Request) {
+	*h.calls = append(*h.calls, "reports")
+}
+
+func Test_Err(t *testing.T) {
+	failure := errors.New("failure")
+	tests := []struct {
+		mw       Middleware
+		handler  HandlerFunc
+		expected func(error) bool
+	}{
+		{testMiddleware, func(c context.Context, w http.ResponseWriter, r *http.Request) {}, func(err error) bool {
+			return err == nil
+		}},
+		{Wrap(func(c context.Context, w http.ResponseWriter, r *http.Request) (context.Context, error) {
+			return c, failure
+		}), nil, func(err error) bool {
+			return errors.Is(err, failure)
+		}},
+		{Recover(), func(c context.Context, w http.ResponseWriter, r *http.Request) {
+			panic("foobar")
+		}, func(err error) bool {
+			var panicErr *PanicError
+			return errors.As(err, &panicErr)
+		}},
+	}
+
+	for i, test := range tests {
+		var err error
+		q := New(func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+			Defer(c, func() { err = Err(c) })
+			return c
+		}, test.mw)
+		q.ContextFunc = background
+		q.Then(test.handler)(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+		if !test.expected(err) {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		}
+	}
+}
+
+func Test_ErrUnrecoveredPanic(t *testing.T) {
+	var err error
+	q := New(func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		Defer(c, func() { err = Err(c) })
+		return c
+	})
+	q.ContextFunc = background
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		panic("foobar")
+	})
+
+	func() {
+		defer func() { recover() }()
+		fn(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+
+	if err != ErrPanic {
+		t.Error("expected ErrPanic: ", err)
+	}
+}
+
+func Benchmark_ServeHTTP(b *testing.B) {
+	q := New()
+	for i := 0; i < 10; i++ {
+		q.Add(testMiddleware)
+	}
+	q.ContextFunc = background
+	q.Default(func(c context.Context, w http.ResponseWriter, r *http.Request) {})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.ServeHTTP(w, r)
+	}
+}
+
+func Benchmark_Chain(b *testing.B) {
+	fns := make([]Middleware, 10)
+	for i := range fns {
+		fns[i] = testMiddleware
+	}
+	c := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		chain(fns)(c, nil, nil)
+	}
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/realip"
+	"google.golang.org/appengine/memcache"
+)
+
+// Store counts the requests made within a window
+type Store interface {
+	// Increment adds one to the count of the key, returning the new count. The
+	// count only needs to be kept for the ttl provided.
+	Increment(c context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// Config defines how many requests are allowed in each window
+type Config struct {
+	// Limit is the number of requests allowed within each window
+	Limit int64
+
+	// Window is the length of each window, defaulting to a minute
+	Window time.Duration
+
+	// Key returns the key the requests are counted by, defaulting to the IP address
+	// of the client. The default uses the IP resolved by realip.Resolve, falling
+	// back to the X-AppEngine-User-IP header, since on App Engine r.RemoteAddr is
+	// the address of the front end rather than the client.
+	Key func(context.Context, *http.Request) string
+
+	// Store keeps the request counts, defaulting to App Engine memcache
+	Store Store
+
+	// FailClosed rejects requests when the store is unavailable, rather than
+	// allowing them through
+	FailClosed bool
+}
+
+// Limit returns a middleware that rejects requests with a 429 once the limit of
+// requests within the current window is exceeded. The Retry-After header of the
+// rejected response holds the seconds until the next window starts.
+//	q := quincy.New(ratelimit.Limit(ratelimit.Config{Limit: 100}))
+func Limit(cfg Config) quincy.Middleware {
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.Key == nil {
+		cfg.Key = clientIP
+	}
+	if cfg.Store == nil {
+		cfg.Store = MemcacheStore{}
+	}
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		now := time.Now()
+		window := now.UnixNano() / int64(cfg.Window)
+		reset := time.Unix(0, (window+1)*int64(cfg.Window)).Sub(now)
+
+		// each window is counted under its own key so the count never has to be
+		// reset, which would race with the increments
+		key := fmt.Sprintf("ratelimit:%s:%d", cfg.Key(c, r), window)
+		count, err := cfg.Store.Increment(c, key, cfg.Window)
+		if err != nil {
+			if cfg.FailClosed {
+				return quincy.Abort(c, w, http.StatusServiceUnavailable)
+			}
+			return c
+		}
+
+		if count > cfg.Limit {
+			w.Header().Set("Retry-After", strconv.Itoa(int(reset.Seconds())+1))
+			return quincy.Abort(c, w, http.StatusTooManyRequests)
+		}
+		return c
+	}
+}
+
+// returns the IP address of the client making the request
+func clientIP(c context.Context, r *http.Request) string {
+	if ip := realip.ClientIP(c); ip != "" {
+		return ip
+	}
+	if ip := r.Header.Get("X-AppEngine-User-IP"); ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// MemcacheStore counts requests within App Engine memcache
+type MemcacheStore struct{}
+
+// Increment adds one to the count held in memcache
+func (MemcacheStore) Increment(c context.Context, key string, ttl time.Duration) (int64, error) {
+	// Add only sets the expiration if the key is missing, after which Increment is
+	// atomic across instances
+	err := memcache.Add(c, &memcache.Item{Key: key, Value: []byte("0"), Expiration: ttl})
+	if err != nil && err != memcache.ErrNotStored {
+		return 0, err
+	}
+	n, err := memcache.Increment(c, key, 1, 0)
+	return int64(n), err
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/realip"
+)
+
+type memStore map[string]int64
+
+func (m memStore) Increment(c context.Context, key string, ttl time.Duration) (int64, error) {
+	m[key]++
+	return m[key], nil
+}
+
+type failingStore struct{}
+
+func (failingStore) Increment(c context.Context, key string, ttl time.Duration) (int64, error) {
+	return 0, errors.New("unavailable")
+}
+
+func Test_Limit(t *testing.T) {
+	mw := Limit(Config{Limit: 2, Window: time.Hour, Store: memStore{}})
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		c := mw(context.Background(), w, httptest.NewRequest("GET", "/", nil))
+		if c.Err() != nil {
+			t.Error("request within the limit was rejected: ", i)
+			return
+		}
+	}
+
+	w := httptest.NewRecorder()
+	c := mw(context.Background(), w, httptest.NewRequest("GET", "/", nil))
+	if c.Err() == nil {
+		t.Error("request over the limit was allowed")
+	}
+	if w.Code != http.StatusTooManyRequests {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("missing Retry-After header")
+	}
+}
+
+// returns a request from the client arriving through the App Engine front end
+func clientRequest(ip string) *http.Request {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "169.254.1.1:1234"
+	r.Header.Set("X-AppEngine-User-IP", ip)
+	return r
+}
+
+func Test_LimitPerKey(t *testing.T) {
+	mw := Limit(Config{Limit: 1, Window: time.Hour, Store: memStore{}})
+
+	if mw(context.Background(), httptest.NewRecorder(), clientRequest("203.0.113.1")).Err() != nil {
+		t.Error("first client was rejected")
+	}
+	if mw(context.Background(), httptest.NewRecorder(), clientRequest("203.0.113.2")).Err() != nil {
+		t.Error("second client was rejected")
+	}
+	if mw(context.Background(), httptest.NewRecorder(), clientRequest("203.0.113.1")).Err() == nil {
+		t.Error("first client over the limit was allowed")
+	}
+}
+
+func Test_LimitRealIP(t *testing.T) {
+	q := quincy.New(realip.Resolve(realip.Config{TrustForwardedFor: true}), Limit(Config{Limit: 1, Window: time.Hour, Store: memStore{}}))
+	for i, ip := range []string{"203.0.113.1", "203.0.113.2"} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "169.254.1.1:1234"
+		r.Header.Set("X-Forwarded-For", ip)
+		if err := q.RunE(context.Background(), httptest.NewRecorder(), r); err != nil {
+			t.Error("client was rejected: ", i, err)
+		}
+	}
+}
+
+func Test_LimitCustomKey(t *testing.T) {
+	key := func(c context.Context, r *http.Request) string {
+		return r.Header.Get("X-API-Key")
+	}
+	mw := Limit(Config{Limit: 1, Window: time.Hour, Store: memStore{}, Key: key})
+
+	for _, ip := range []string{"203.0.113.1", "203.0.113.2"} {
+		r := clientRequest(ip)
+		r.Header.Set("X-API-Key", "shared")
+		c := mw(context.Background(), httptest.NewRecorder(), r)
+		if ip == "203.0.113.2" && c.Err() == nil {
+			t.Error("requests should be counted by the custom key")
+		}
+	}
+}
+
+func Test_LimitStoreUnavailable(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+
+	open := Limit(Config{Limit: 1, Store: failingStore{}})
+	if open(context.Background(), httptest.NewRecorder(), r).Err() != nil {
+		t.Error("request should be allowed when failing open")
+	}
+
+	closed := Limit(Config{Limit: 1, Store: failingStore{}, FailClosed: true})
+	if closed(context.Background(), httptest.NewRecorder(), r).Err() == nil {
+		t.Error("request should be rejected when failing closed")
+	}
+}
@@ -0,0 +1,87 @@
+package realip
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// Config defines which sources of the client IP are trusted
+type Config struct {
+	// TrustForwardedFor allows the X-Forwarded-For header to be used when the App
+	// Engine header is missing. Clients can set the header themselves, so it must
+	// only be trusted when a proxy in front of the app replaces it.
+	TrustForwardedFor bool
+
+	// RewriteRemoteAddr replaces the host of r.RemoteAddr with the resolved IP
+	RewriteRemoteAddr bool
+}
+
+type clientIP string
+
+// Resolve returns a middleware that stores the IP of the client on the context.
+// The X-AppEngine-User-IP header, which App Engine strips from inbound requests
+// before setting, is preferred, followed by the left-most public address of the
+// X-Forwarded-For header when trusted, and finally r.RemoteAddr.
+//	q := quincy.New(realip.Resolve(realip.Config{}))
+func Resolve(cfg Config) quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		host, port, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+
+		ip, ok := parse(r.Header.Get("X-AppEngine-User-IP"))
+		if !ok && cfg.TrustForwardedFor {
+			ip, ok = forwardedFor(r.Header.Get("X-Forwarded-For"))
+		}
+		if !ok {
+			ip, ok = parse(host)
+		}
+		if !ok {
+			return c
+		}
+
+		if cfg.RewriteRemoteAddr {
+			if port == "" {
+				r.RemoteAddr = ip.String()
+			} else {
+				r.RemoteAddr = net.JoinHostPort(ip.String(), port)
+			}
+		}
+		return quincy.WithValue(c, clientIP(ip.String()))
+	}
+}
+
+// ClientIP returns the IP of the client resolved by Resolve, or an empty string if
+// it was not resolved
+func ClientIP(c context.Context) string {
+	ip, _ := quincy.Value[clientIP](c)
+	return string(ip)
+}
+
+// returns the left-most public address of the X-Forwarded-For header, skipping
+// any private addresses added by internal proxies
+func forwardedFor(header string) (netip.Addr, bool) {
+	for _, part := range strings.Split(header, ",") {
+		ip, ok := parse(part)
+		if ok && !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() {
+			return ip, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// parses an IPv4 or IPv6 address, removing any IPv6 brackets and zone
+func parse(s string) (netip.Addr, bool) {
+	s = strings.Trim(strings.TrimSpace(s), "[]")
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ip.WithZone("").Unmap(), true
+}
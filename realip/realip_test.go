@@ -0,0 +1,54 @@
+package realip
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Resolve(t *testing.T) {
+	tests := []struct {
+		cfg        Config
+		remoteAddr string
+		appEngine  string
+		forwarded  string
+		expected   string
+	}{
+		{Config{}, "10.0.0.1:1234", "", "", "10.0.0.1"},
+		{Config{}, "10.0.0.1:1234", "203.0.113.7", "", "203.0.113.7"},
+		{Config{}, "10.0.0.1:1234", "", "203.0.113.7", "10.0.0.1"},
+		{Config{TrustForwardedFor: true}, "10.0.0.1:1234", "", "192.168.0.1, 203.0.113.7, 198.51.100.1", "203.0.113.7"},
+		{Config{TrustForwardedFor: true}, "10.0.0.1:1234", "203.0.113.7", "198.51.100.1", "203.0.113.7"},
+		{Config{TrustForwardedFor: true}, "10.0.0.1:1234", "", "192.168.0.1", "10.0.0.1"},
+		{Config{}, "[2001:db8::1]:443", "", "", "2001:db8::1"},
+		{Config{}, "10.0.0.1:1234", "2001:db8::2", "", "2001:db8::2"},
+	}
+
+	for i, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = test.remoteAddr
+		if test.appEngine != "" {
+			r.Header.Set("X-AppEngine-User-IP", test.appEngine)
+		}
+		if test.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", test.forwarded)
+		}
+
+		c := Resolve(test.cfg)(context.Background(), nil, r)
+		if ip := ClientIP(c); ip != test.expected {
+			t.Errorf("test %d: expected %s, got %s", i, test.expected, ip)
+		}
+	}
+}
+
+func Test_RewriteRemoteAddr(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-AppEngine-User-IP", "203.0.113.7")
+
+	Resolve(Config{RewriteRemoteAddr: true})(context.Background(), nil, r)
+
+	if r.RemoteAddr != "203.0.113.7:1234" {
+		t.Error("remote address not rewritten: ", r.RemoteAddr)
+	}
+}
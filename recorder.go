@@ -0,0 +1,116 @@
+package quincy
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+)
+
+// StatusRecorder wraps a ResponseWriter to record the status code and the number
+// of bytes written. The ResponseWriter passed to the middleware and handlers by
+// Then and Handle is a *StatusRecorder, which allows middleware such as loggers
+// to read the outcome of the request.
+//	q.Finally(func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+//		rec := w.(*quincy.StatusRecorder)
+//		log.Infof(c, "%s %s %d %d", r.Method, r.URL.Path, rec.Status(), rec.Written())
+//		return c
+//	})
+type StatusRecorder struct {
+	Wrapper
+	status  int
+	written int64
+}
+
+// NewStatusRecorder returns a StatusRecorder wrapping w, or w itself if it is
+// already a StatusRecorder
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	if rec, ok := w.(*StatusRecorder); ok {
+		return rec
+	}
+	return &StatusRecorder{Wrapper: Wrapper{ResponseWriter: w}}
+}
+
+// Status returns the status code of the response, or zero if the response has not
+// been started
+func (s *StatusRecorder) Status() int {
+	return s.status
+}
+
+// Written returns the number of bytes of the response body written
+func (s *StatusRecorder) Written() int64 {
+	return s.written
+}
+
+// WriteHeader records the status code before writing it to the underlying
+// ResponseWriter. Only the first status is written, so a middleware or OnError
+// hook writing a status after the response has started doesn't replace it or
+// cause a superfluous WriteHeader call. Informational 1xx statuses are passed on
+// without being recorded.
+func (s *StatusRecorder) WriteHeader(status int) {
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		s.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if s.status != 0 {
+		return
+	}
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the number of bytes written, with a status of 200 being recorded
+// if none was written beforehand
+func (s *StatusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.written += int64(n)
+	return n, err
+}
+
+// Flush sends any buffered data to the client if the underlying ResponseWriter
+// supports flushing
+func (s *StatusRecorder) Flush() {
+	f, ok := s.ResponseWriter.(http.Flusher)
+	if !ok {
+		return
+	}
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	f.Flush()
+}
+
+// Hijack takes over the connection if the underlying ResponseWriter supports it,
+// returning http.ErrNotSupported otherwise. The status is recorded as 101 since
+// the response is then handled outside of the chain.
+func (s *StatusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := s.Wrapper.Hijack()
+	if err == nil && s.status == 0 {
+		s.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+type writerKey struct{}
+
+// WithWriter returns a context that replaces the ResponseWriter passed to the rest
+// of the chain and to the final handler. Since a middleware only returns a context
+// this allows it to wrap the ResponseWriter, such as to compress the response. The
+// OnError hook and the Finally middleware continue to receive the original writer.
+//	gw := newGzipWriter(w)
+//	quincy.Defer(c, gw.Close)
+//	return quincy.WithWriter(c, gw)
+func WithWriter(c context.Context, w http.ResponseWriter) context.Context {
+	return context.WithValue(c, writerKey{}, w)
+}
+
+// returns the ResponseWriter set with WithWriter, falling back to w
+func writerFrom(c context.Context, w http.ResponseWriter) http.ResponseWriter {
+	if ww, ok := c.Value(writerKey{}).(http.ResponseWriter); ok {
+		return ww
+	}
+	return w
+}
@@ -0,0 +1,261 @@
+package quincy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_StatusRecorder(t *testing.T) {
+	rec := NewStatusRecorder(httptest.NewRecorder())
+	rec.WriteHeader(http.StatusNotFound)
+	rec.Write([]byte("foo"))
+	rec.Write([]byte("bar"))
+
+	if rec.Status() != http.StatusNotFound {
+		t.Error("invalid status: ", rec.Status())
+	}
+	if rec.Written() != 6 {
+		t.Error("invalid byte count: ", rec.Written())
+	}
+}
+
+func Test_StatusRecorderImplicitStatus(t *testing.T) {
+	rec := NewStatusRecorder(httptest.NewRecorder())
+	if rec.Status() != 0 {
+		t.Error("status should not be set before writing")
+	}
+
+	rec.Write([]byte("foo"))
+	if rec.Status() != http.StatusOK {
+		t.Error("invalid status: ", rec.Status())
+	}
+}
+
+func Test_StatusRecorderInChain(t *testing.T) {
+	var status int
+	q := New()
+	q.ContextFunc = background
+	q.Finally(func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		status = w.(*StatusRecorder).Status()
+		return c
+	})
+
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	fn(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if status != http.StatusNotFound {
+		t.Error("invalid status: ", status)
+	}
+}
+
+type upperWriter struct {
+	http.ResponseWriter
+}
+
+func (u upperWriter) Write(b []byte) (int, error) {
+	return u.ResponseWriter.Write(bytes.ToUpper(b))
+}
+
+func Test_WithWriter(t *testing.T) {
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return WithWriter(c, upperWriter{w})
+	}
+
+	q := New(mw)
+	q.ContextFunc = background
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("foobar"))
+	})
+
+	w := httptest.NewRecorder()
+	fn(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Body.String() != "FOOBAR" {
+		t.Error("handler did not receive the replaced writer: ", w.Body.String())
+	}
+}
+
+func Test_StatusRecorderFlush(t *testing.T) {
+	q := New()
+	q.ContextFunc = background
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Error("writer should implement http.Flusher")
+			return
+		}
+		w.Write([]byte("foo"))
+		f.Flush()
+	})
+
+	w := httptest.NewRecorder()
+	fn(w, httptest.NewRequest("GET", "/", nil))
+
+	if !w.Flushed {
+		t.Error("flush was not delegated to the underlying writer")
+	}
+}
+
+// hijackRecorder is a ResponseRecorder that supports taking over the connection
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return h.conn, bufio.NewReadWriter(bufio.NewReader(h.conn), bufio.NewWriter(h.conn)), nil
+}
+
+func Test_StatusRecorderHijack(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	rec := NewStatusRecorder(&hijackRecorder{httptest.NewRecorder(), server})
+
+	var w http.ResponseWriter = rec
+	h, ok := w.(http.Hijacker)
+	if !ok {
+		t.Error("writer should implement http.Hijacker")
+		return
+	}
+	conn, _, err := h.Hijack()
+	if err != nil {
+		t.Error("unexpected error: ", err)
+	}
+	if conn != server {
+		t.Error("hijack was not delegated to the underlying writer")
+	}
+	if rec.Status() != http.StatusSwitchingProtocols {
+		t.Error("invalid status: ", rec.Status())
+	}
+}
+
+func Test_StatusRecorderHijackNotSupported(t *testing.T) {
+	rec := NewStatusRecorder(httptest.NewRecorder())
+	if _, _, err := rec.Hijack(); err != http.ErrNotSupported {
+		t.Error("invalid error: ", err)
+	}
+	if rec.Status() != 0 {
+		t.Error("status should not be set: ", rec.Status())
+	}
+}
+
+// closeNotifyRecorder is a ResponseRecorder that reports when the client goes away
+type closeNotifyRecorder struct {
+	*httptest.ResponseRecorder
+	closed chan bool
+}
+
+func (cn *closeNotifyRecorder) CloseNotify() <-chan bool {
+	return cn.closed
+}
+
+func Test_StatusRecorderCloseNotify(t *testing.T) {
+	closed := make(chan bool, 1)
+	q := New()
+	q.ContextFunc = background
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		cn, ok := w.(http.CloseNotifier)
+		if !ok {
+			t.Error("writer should implement http.CloseNotifier")
+			return
+		}
+		if cn.CloseNotify() != (<-chan bool)(closed) {
+			t.Error("close notify was not delegated to the underlying writer")
+		}
+	})
+
+	fn(&closeNotifyRecorder{httptest.NewRecorder(), closed}, httptest.NewRequest("GET", "/", nil))
+}
+
+// pushRecorder is a ResponseRecorder that records the pushed targets
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (p *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	p.pushed = append(p.pushed, target)
+	return nil
+}
+
+func Test_StatusRecorderPush(t *testing.T) {
+	w := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	q := New()
+	q.ContextFunc = background
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		p, ok := w.(http.Pusher)
+		if !ok {
+			t.Error("writer should implement http.Pusher")
+			return
+		}
+		if err := p.Push("/app.css", nil); err != nil {
+			t.Error("unexpected error: ", err)
+		}
+	})
+
+	fn(w, httptest.NewRequest("GET", "/", nil))
+
+	if len(w.pushed) != 1 || w.pushed[0] != "/app.css" {
+		t.Error("push was not delegated to the underlying writer: ", w.pushed)
+	}
+}
+
+func Test_StatusRecorderPushNotSupported(t *testing.T) {
+	rec := NewStatusRecorder(httptest.NewRecorder())
+	if err := rec.Push("/app.css", nil); err != http.ErrNotSupported {
+		t.Error("invalid error: ", err)
+	}
+}
+
+// headerCounter is a ResponseRecorder that counts the calls to WriteHeader
+type headerCounter struct {
+	*httptest.ResponseRecorder
+	calls int
+}
+
+func (h *headerCounter) WriteHeader(status int) {
+	h.calls++
+	h.ResponseRecorder.WriteHeader(status)
+}
+
+func Test_StatusRecorderWriteHeaderOnce(t *testing.T) {
+	forbid := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return Abort(c, w, http.StatusForbidden)
+	}
+	q := New(forbid)
+	q.ContextFunc = background
+	q.OnError(func(c context.Context, w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	w := &headerCounter{ResponseRecorder: httptest.NewRecorder()}
+	q.Then(nil)(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusForbidden {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if w.calls != 1 {
+		t.Error("WriteHeader should be called once: ", w.calls)
+	}
+}
+
+func Test_StatusRecorderInformational(t *testing.T) {
+	w := &headerCounter{ResponseRecorder: httptest.NewRecorder()}
+	rec := NewStatusRecorder(w)
+	rec.WriteHeader(http.StatusEarlyHints)
+	rec.WriteHeader(http.StatusCreated)
+
+	if rec.Status() != http.StatusCreated {
+		t.Error("invalid status: ", rec.Status())
+	}
+	if w.calls != 2 {
+		t.Error("informational status should be passed on: ", w.calls)
+	}
+}
@@ -0,0 +1,69 @@
+package quincy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// PanicError is passed to the OnError hook when Recover catches a panic, holding
+// the recovered value and the stack of the goroutine that panicked
+//	var panicErr *quincy.PanicError
+//	if errors.As(err, &panicErr) {
+//		log.Criticalf(c, "%v\n%s", panicErr.Value, panicErr.Stack)
+//	}
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("quincy: recovered from panic: %v", e.Value)
+}
+
+// Unwrap returns the recovered value if it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+type recoverConfig struct {
+	showStack bool
+}
+
+// RecoverOption configures the recover middleware
+type RecoverOption func(*recoverConfig)
+
+// ShowStack writes the panic and its stack as the body of the 500 response. It
+// exposes the internals of the app, so it must only be used in development.
+//	q := quincy.New(quincy.Recover(quincy.ShowStack()))
+func ShowStack() RecoverOption {
+	return func(cfg *recoverConfig) {
+		cfg.showStack = true
+	}
+}
+
+// Recover returns a middleware that recovers from a panic within any of the
+// middleware that follow it, or within the final handler, by passing a PanicError
+// on to the OnError hook and writing a 500 if the hook did not respond.
+//
+// Since each middleware returns before the chain calls the next, a deferred recover
+// within the middleware itself would not cover the rest of the chain. Recover
+// instead flags the request so Then and Handle, which wrap the whole chain,
+// recover the panic. Panics raised by middleware registered ahead of Recover, or
+// by a chain executed with Run, are not recovered.
+//	q := quincy.New(logger, quincy.Recover(), auth)
+func Recover(opts ...RecoverOption) Middleware {
+	var cfg recoverConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		if st := stateFrom(c); st != nil {
+			st.recover = true
+			st.showStack = cfg.showStack
+		}
+		return c
+	}
+}
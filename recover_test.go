@@ -0,0 +1,104 @@
+package quincy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_Recover(t *testing.T) {
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		panic("foobar")
+	}
+
+	q := New(Recover(), mw)
+	q.ContextFunc = background
+
+	var hookErr error
+	q.OnError(func(c context.Context, w http.ResponseWriter, r *http.Request, err error) {
+		hookErr = err
+	})
+
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called")
+	})
+
+	w := httptest.NewRecorder()
+	fn(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if hookErr == nil {
+		t.Error("recovered panic was not passed to the error hook")
+	}
+}
+
+func Test_RecoverHandler(t *testing.T) {
+	q := New(Recover())
+	q.ContextFunc = background
+
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		panic("foobar")
+	})
+
+	w := httptest.NewRecorder()
+	fn(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Error("Invalid response status: ", w.Code)
+	}
+}
+
+func Test_RecoverPanicError(t *testing.T) {
+	q := New(Recover())
+	q.ContextFunc = background
+
+	var hookErr error
+	q.OnError(func(c context.Context, w http.ResponseWriter, r *http.Request, err error) {
+		hookErr = err
+	})
+
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		panic("foobar")
+	})
+
+	w := httptest.NewRecorder()
+	fn(w, httptest.NewRequest("GET", "/", nil))
+
+	var panicErr *PanicError
+	if !errors.As(hookErr, &panicErr) {
+		t.Fatal("expected a PanicError: ", hookErr)
+	}
+	if panicErr.Value != "foobar" {
+		t.Error("invalid panic value: ", panicErr.Value)
+	}
+	if !strings.Contains(string(panicErr.Stack), "Test_RecoverPanicError") {
+		t.Error("stack does not include the panicking function")
+	}
+	if w.Body.Len() != 0 {
+		t.Error("stack should not be written to the response: ", w.Body.String())
+	}
+}
+
+func Test_RecoverShowStack(t *testing.T) {
+	q := New(Recover(ShowStack()))
+	q.ContextFunc = background
+
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		panic("foobar")
+	})
+
+	w := httptest.NewRecorder()
+	fn(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "foobar") || !strings.Contains(w.Body.String(), "Test_RecoverShowStack") {
+		t.Error("stack not written to the response: ", w.Body.String())
+	}
+}
@@ -0,0 +1,60 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// Header is the request and response header carrying the request ID
+const Header = "X-Request-ID"
+
+// the longest inbound ID that is accepted, with longer ones being replaced
+const maxLength = 200
+
+type requestID string
+
+// Inject returns a middleware that stores the ID of the request on the context and
+// sets it on the response header. An inbound X-Request-ID header is passed through,
+// otherwise a random ID is generated.
+//	q := quincy.New(requestid.Inject(), logger.Log())
+func Inject() quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		id := r.Header.Get(Header)
+		if !valid(id) {
+			id = generate()
+		}
+		w.Header().Set(Header, id)
+		return quincy.WithValue(c, requestID(id))
+	}
+}
+
+// From returns the ID of the request, or an empty string if Inject did not run
+//	id := requestid.From(c)
+func From(c context.Context) string {
+	id, _ := quincy.Value[requestID](c)
+	return string(id)
+}
+
+// returns a random 128 bit ID, which makes collisions between requests improbable
+func generate() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// reports whether the inbound ID can be safely passed on to logs and responses
+func valid(id string) bool {
+	if len(id) == 0 || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
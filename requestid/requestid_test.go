@@ -0,0 +1,42 @@
+package requestid
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_InboundID(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(Header, "abc-123")
+	w := httptest.NewRecorder()
+
+	c := Inject()(context.Background(), w, r)
+
+	if From(c) != "abc-123" {
+		t.Error("inbound ID not passed through: ", From(c))
+	}
+	if w.Header().Get(Header) != "abc-123" {
+		t.Error("response header not set")
+	}
+}
+
+func Test_GeneratedID(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	c := Inject()(context.Background(), w, r)
+
+	id := From(c)
+	if len(id) != 32 {
+		t.Error("invalid generated ID: ", id)
+	}
+	if w.Header().Get(Header) != id {
+		t.Error("response header does not match the generated ID")
+	}
+
+	c = Inject()(context.Background(), httptest.NewRecorder(), r)
+	if From(c) == id {
+		t.Error("generated IDs should be unique")
+	}
+}
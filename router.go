@@ -0,0 +1,248 @@
+package quincy
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Router dispatches requests by method and path to handlers that each run their
+// own chain, made up of the middleware given to NewRouter and Use followed by the
+// middleware of the route. Path segments starting with a colon, such as
+// /users/:id, match any value, which is set as a param to be read with
+// ParamsFrom, while the pattern of the route is set for RoutePattern. When several
+// routes match a path, static segments are preferred over params from the first
+// segment on, so /users/new is matched ahead of /users/:id whatever the order
+// they were registered in. Routes must be registered before the router serves
+// requests.
+//	router := quincy.NewRouter(logger.Log())
+//	router.Get("/users/:id", showUser)
+//	router.Post("/users", createUser, auth)
+//	http.Handle("/", router)
+type Router struct {
+	base   *Q
+	routes []*route
+
+	// NotFound handles requests that match no route, defaulting to http.NotFound
+	NotFound http.Handler
+}
+
+// route is a pattern registered for a method
+type route struct {
+	method   string
+	pattern  string
+	segments []string
+	serve    func(http.ResponseWriter, *http.Request)
+}
+
+// match is the route matched by the router, which is handed to the chain of the
+// route on the request context
+type match struct {
+	pattern string
+	params  Params
+}
+
+type matchKey struct{}
+
+// NewRouter creates a router whose routes run the middleware provided ahead of
+// their own
+func NewRouter(fns ...Middleware) *Router {
+	return &Router{base: New(fns...)}
+}
+
+// Chain returns the base chain of the router, allowing its ContextFunc and
+// OnError hook to be set before routes are registered
+func (rt *Router) Chain() *Q {
+	return rt.base
+}
+
+// Use adds middleware that run ahead of the middleware of each route. It panics
+// if routes have already been registered, since they would not include it.
+func (rt *Router) Use(fns ...Middleware) {
+	if len(rt.routes) > 0 {
+		panic("quincy: Use must be called before routes are registered")
+	}
+	rt.base.Add(fns...)
+}
+
+// Get registers the handler, run after the middleware provided, for GET and HEAD
+// requests matching the pattern
+func (rt *Router) Get(pattern string, fn HandlerFunc, fns ...Middleware) {
+	rt.Handle("GET", pattern, fn, fns...)
+}
+
+// Post registers the handler, run after the middleware provided, for POST
+// requests matching the pattern
+func (rt *Router) Post(pattern string, fn HandlerFunc, fns ...Middleware) {
+	rt.Handle("POST", pattern, fn, fns...)
+}
+
+// Put registers the handler, run after the middleware provided, for PUT requests
+// matching the pattern
+func (rt *Router) Put(pattern string, fn HandlerFunc, fns ...Middleware) {
+	rt.Handle("PUT", pattern, fn, fns...)
+}
+
+// Delete registers the handler, run after the middleware provided, for DELETE
+// requests matching the pattern
+func (rt *Router) Delete(pattern string, fn HandlerFunc, fns ...Middleware) {
+	rt.Handle("DELETE", pattern, fn, fns...)
+}
+
+// Handle registers the handler, run after the middleware provided, for requests
+// of the method matching the pattern
+func (rt *Router) Handle(method, pattern string, fn HandlerFunc, fns ...Middleware) {
+	q := rt.base.Clone()
+	q.Prepend(routeMatch)
+	q.Add(fns...)
+	rt.routes = append(rt.routes, &route{
+		method:   method,
+		pattern:  pattern,
+		segments: split(pattern),
+		serve:    q.Then(fn),
+	})
+}
+
+// ServeHTTP runs the most specific route matching the request. A 405 is written,
+// along with the Allow header, if the path matches routes of other methods only.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := split(r.URL.Path)
+	var (
+		best    *route
+		params  Params
+		allowed []string
+	)
+	for _, rte := range rt.routes {
+		p, ok := rte.match(segments)
+		if !ok {
+			continue
+		}
+		if rte.method != r.Method && !(rte.method == "GET" && r.Method == "HEAD") {
+			if !slices.Contains(allowed, rte.method) {
+				allowed = append(allowed, rte.method)
+			}
+			continue
+		}
+		if best == nil || rte.before(best) {
+			best, params = rte, p
+		}
+	}
+
+	if best != nil {
+		m := &match{pattern: best.pattern, params: params}
+		best.serve(w, r.WithContext(context.WithValue(r.Context(), matchKey{}, m)))
+		return
+	}
+
+	if len(allowed) > 0 {
+		sort.Strings(allowed)
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if rt.NotFound != nil {
+		rt.NotFound.ServeHTTP(w, r)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// returns the params of the path segments if they match the route
+func (rte *route) match(segments []string) (Params, bool) {
+	if len(segments) != len(rte.segments) {
+		return nil, false
+	}
+	var p Params
+	for i, s := range rte.segments {
+		if strings.HasPrefix(s, ":") {
+			if p == nil {
+				p = Params{}
+			}
+			p[s[1:]] = segments[i]
+			continue
+		}
+		if s != segments[i] {
+			return nil, false
+		}
+	}
+	return p, true
+}
+
+// reports whether the route is more specific than the other, being the first to
+// have a static segment where the other has a param
+func (rte *route) before(other *route) bool {
+	for i, s := range rte.segments {
+		static, otherStatic := !strings.HasPrefix(s, ":"), !strings.HasPrefix(other.segments[i], ":")
+		if static != otherStatic {
+			return static
+		}
+	}
+	return false
+}
+
+// sets the pattern and params matched by the router on the chain context
+func routeMatch(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+	m, ok := r.Context().Value(matchKey{}).(*match)
+	if !ok {
+		return c
+	}
+	c = WithRoutePattern(c, m.pattern)
+	if m.params != nil {
+		c = WithParams(c, m.params)
+	}
+	return c
+}
+
+type routePatternKey struct{}
+
+// WithRoutePattern returns a context that carries the pattern of the route that
+// matched the request, such as /users/:id, which is set by Router and the router
+// adapters. Within Then or Handle the pattern is kept with the request, so it is
+// also seen by middleware that ran before it was set, such as those reporting the
+// request once it completes.
+//	c = quincy.WithRoutePattern(c, "/users/:id")
+func WithRoutePattern(c context.Context, pattern string) context.Context {
+	if st := stateFrom(c); st != nil {
+		st.pattern = pattern
+		return c
+	}
+	return context.WithValue(c, routePatternKey{}, pattern)
+}
+
+// LookupRoutePattern returns the route pattern set with WithRoutePattern and
+// whether one was set
+//	if pattern, ok := quincy.LookupRoutePattern(c); ok {
+//		labels["route"] = pattern
+//	}
+func LookupRoutePattern(c context.Context) (string, bool) {
+	if st := stateFrom(c); st != nil && st.pattern != "" {
+		return st.pattern, true
+	}
+	pattern, ok := c.Value(routePatternKey{}).(string)
+	return pattern, ok
+}
+
+// RoutePattern returns the route pattern set with WithRoutePattern, falling back
+// to the raw path of the request when none was set. Outside of Then or Handle there
+// is no request to fall back to, in which case it returns an empty string.
+//	log.Infof(c, "%s %s", r.Method, quincy.RoutePattern(c))
+func RoutePattern(c context.Context) string {
+	if pattern, ok := LookupRoutePattern(c); ok {
+		return pattern
+	}
+	if st := stateFrom(c); st != nil {
+		return st.path
+	}
+	return ""
+}
+
+// returns the segments of the path, ignoring the leading and trailing slashes
+func split(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
@@ -0,0 +1,154 @@
+package quincy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestRouter(calls *[]string) *Router {
+	rt := NewRouter(record("global", calls))
+	rt.Chain().ContextFunc = background
+	rt.Get("/users/:id", func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		*calls = append(*calls, "show "+ParamsFrom(c).Get("id"))
+	})
+	rt.Post("/users", func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		*calls = append(*calls, "create")
+	}, record("auth", calls))
+	rt.Delete("/users/:id", func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		*calls = append(*calls, "delete "+ParamsFrom(c).Get("id"))
+	})
+	return rt
+}
+
+func Test_Router(t *testing.T) {
+	var calls []string
+	rt := newTestRouter(&calls)
+
+	requests := []struct{ method, path string }{
+		{"GET", "/users/42"},
+		{"POST", "/users"},
+		{"DELETE", "/users/7/"},
+		{"HEAD", "/users/1"},
+	}
+	for _, req := range requests {
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest(req.method, req.path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s %s: invalid response status: %d", req.method, req.path, w.Code)
+		}
+	}
+
+	expected := "global,show 42,global,auth,create,global,delete 7,global,show 1"
+	if strings.Join(calls, ",") != expected {
+		t.Error("invalid calls: ", calls)
+	}
+}
+
+func Test_RouterMethodNotAllowed(t *testing.T) {
+	var calls []string
+	rt := newTestRouter(&calls)
+
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest("PUT", "/users/42", nil))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "DELETE, GET" {
+		t.Error("invalid Allow header: ", allow)
+	}
+	if len(calls) != 0 {
+		t.Error("no chain should run: ", calls)
+	}
+}
+
+func Test_RouterStaticFirst(t *testing.T) {
+	var calls []string
+	rt := newTestRouter(&calls)
+	rt.Get("/users/new", func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "new")
+	})
+	rt.Get("/:section/:id/edit", func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "edit "+ParamsFrom(c).Get("section"))
+	})
+	rt.Get("/users/:id/edit", func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "edit user "+ParamsFrom(c).Get("id"))
+	})
+
+	for _, path := range []string{"/users/new", "/users/42", "/users/42/edit", "/posts/1/edit"} {
+		rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	expected := "global,new,global,show 42,global,edit user 42,global,edit posts"
+	if strings.Join(calls, ",") != expected {
+		t.Error("invalid calls: ", calls)
+	}
+
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest("PUT", "/users/new", nil))
+	if allow := w.Header().Get("Allow"); allow != "DELETE, GET" {
+		t.Error("invalid Allow header: ", allow)
+	}
+}
+
+func Test_RouterNotFound(t *testing.T) {
+	var calls []string
+	rt := newTestRouter(&calls)
+
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest("GET", "/accounts/42", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Error("Invalid response status: ", w.Code)
+	}
+}
+
+func Test_RouterUseAfterRoutes(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Use after registering routes should panic")
+		}
+	}()
+
+	var calls []string
+	newTestRouter(&calls).Use(testMiddleware)
+}
+
+func Test_RoutePattern(t *testing.T) {
+	var pattern, path string
+	rt := NewRouter()
+	rt.Chain().ContextFunc = background
+	rt.Get("/users/:id", func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		pattern = RoutePattern(c)
+	})
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	q := New()
+	q.ContextFunc = background
+	q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		if _, ok := LookupRoutePattern(c); ok {
+			t.Error("no route pattern should be set")
+		}
+		path = RoutePattern(c)
+	})(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	if pattern != "/users/:id" {
+		t.Error("invalid route pattern: ", pattern)
+	}
+	if path != "/users/42" {
+		t.Error("route pattern should fall back to the path: ", path)
+	}
+}
+
+func Test_WithRoutePattern(t *testing.T) {
+	c := WithRoutePattern(context.Background(), "/users/:id")
+	if p, ok := LookupRoutePattern(c); !ok || p != "/users/:id" {
+		t.Error("invalid route pattern: ", p)
+	}
+	if RoutePattern(context.Background()) != "" {
+		t.Error("route pattern should be empty outside of Then or Handle")
+	}
+}
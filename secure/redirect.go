@@ -0,0 +1,77 @@
+package secure
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/chrisolsen/quincy"
+)
+
+type redirectConfig struct {
+	temporary bool
+	skip      []string
+}
+
+// RedirectOption configures the HTTPS redirect middleware
+type RedirectOption func(*redirectConfig)
+
+// Temporary redirects with 302 and 307 instead of 301 and 308, so browsers don't
+// remember the redirect
+func Temporary() RedirectOption {
+	return func(cfg *redirectConfig) {
+		cfg.temporary = true
+	}
+}
+
+// Skip sets the path prefixes that are not redirected, replacing the default of
+// App Engine's /_ah/ paths, which are used for health checks and warmup requests
+func Skip(prefixes ...string) RedirectOption {
+	return func(cfg *redirectConfig) {
+		cfg.skip = prefixes
+	}
+}
+
+// RedirectHTTPS returns a middleware that redirects plain HTTP requests to the
+// same URL over HTTPS and ends the chain. Requests for methods other than GET and
+// HEAD are redirected with a 308 or 307 so the method and body are kept.
+//	q := quincy.New(secure.RedirectHTTPS())
+func RedirectHTTPS(opts ...RedirectOption) quincy.Middleware {
+	cfg := redirectConfig{skip: []string{"/_ah/"}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		if https(r) || r.Host == "" {
+			return c
+		}
+		for _, prefix := range cfg.skip {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				return c
+			}
+		}
+
+		u := *r.URL
+		u.Scheme = "https"
+		u.Host = r.Host
+		http.Redirect(w, r, u.String(), redirectStatus(r.Method, cfg.temporary))
+		return quincy.Stop(c)
+	}
+}
+
+// returns the redirect status for the method, with a 301 or 302 changing any
+// other method into a GET
+func redirectStatus(method string, temporary bool) int {
+	safe := method == "GET" || method == "HEAD"
+	switch {
+	case temporary && safe:
+		return http.StatusFound
+	case temporary:
+		return http.StatusTemporaryRedirect
+	case safe:
+		return http.StatusMovedPermanently
+	default:
+		return http.StatusPermanentRedirect
+	}
+}
@@ -0,0 +1,51 @@
+package secure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_RedirectHTTPS(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://example.com/foo?a=1", nil)
+	w := httptest.NewRecorder()
+	RedirectHTTPS()(context.Background(), w, r)
+
+	if w.Code != 301 {
+		t.Error("expected 301, got ", w.Code)
+	}
+	if loc := w.Header().Get("Location"); loc != "https://example.com/foo?a=1" {
+		t.Error("invalid location: ", loc)
+	}
+}
+
+func Test_RedirectHTTPS_Temporary(t *testing.T) {
+	r := httptest.NewRequest("POST", "http://example.com/foo", nil)
+	w := httptest.NewRecorder()
+	RedirectHTTPS(Temporary())(context.Background(), w, r)
+
+	if w.Code != 307 {
+		t.Error("expected 307, got ", w.Code)
+	}
+}
+
+func Test_RedirectHTTPS_PassThrough(t *testing.T) {
+	forwarded := httptest.NewRequest("GET", "http://example.com/", nil)
+	forwarded.Header.Set("X-Forwarded-Proto", "https")
+
+	requests := []*http.Request{
+		httptest.NewRequest("GET", "https://example.com/", nil),
+		httptest.NewRequest("GET", "http://example.com/_ah/health", nil),
+		forwarded,
+	}
+
+	for _, r := range requests {
+		w := httptest.NewRecorder()
+		RedirectHTTPS()(context.Background(), w, r)
+
+		if w.Code != 200 || w.Header().Get("Location") != "" {
+			t.Error("request should not be redirected: ", r.URL)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package secure
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// Disabled can be set as the value of any header within the Config to stop the
+// header from being written
+const Disabled = "-"
+
+// Config holds the values of the protective headers, with empty values being
+// replaced by the defaults
+type Config struct {
+	// ContentTypeOptions defaults to "nosniff"
+	ContentTypeOptions string
+
+	// FrameOptions defaults to "DENY"
+	FrameOptions string
+
+	// ReferrerPolicy defaults to "strict-origin-when-cross-origin"
+	ReferrerPolicy string
+
+	// StrictTransportSecurity is only written for HTTPS requests and defaults to
+	// "max-age=31536000; includeSubDomains"
+	StrictTransportSecurity string
+}
+
+// Headers returns a middleware that writes a baseline of protective headers to
+// each response
+//	q := quincy.New(secure.Headers(secure.Config{FrameOptions: "SAMEORIGIN"}))
+func Headers(cfg Config) quincy.Middleware {
+	contentTypeOptions := value(cfg.ContentTypeOptions, "nosniff")
+	frameOptions := value(cfg.FrameOptions, "DENY")
+	referrerPolicy := value(cfg.ReferrerPolicy, "strict-origin-when-cross-origin")
+	hsts := value(cfg.StrictTransportSecurity, "max-age=31536000; includeSubDomains")
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		h := w.Header()
+		set(h, "X-Content-Type-Options", contentTypeOptions)
+		set(h, "X-Frame-Options", frameOptions)
+		set(h, "Referrer-Policy", referrerPolicy)
+		if https(r) {
+			set(h, "Strict-Transport-Security", hsts)
+		}
+		return c
+	}
+}
+
+// returns the configured value, or the default if none was configured
+func value(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+func set(h http.Header, key, value string) {
+	if value != Disabled {
+		h.Set(key, value)
+	}
+}
+
+// reports whether the request was made over HTTPS, which with App Engine
+// terminating TLS is indicated by the X-Forwarded-Proto header
+func https(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
@@ -0,0 +1,51 @@
+package secure
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Defaults(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	Headers(Config{})(context.Background(), w, r)
+
+	h := w.Header()
+	if h.Get("X-Content-Type-Options") != "nosniff" {
+		t.Error("invalid X-Content-Type-Options: ", h.Get("X-Content-Type-Options"))
+	}
+	if h.Get("X-Frame-Options") != "DENY" {
+		t.Error("invalid X-Frame-Options: ", h.Get("X-Frame-Options"))
+	}
+	if h.Get("Referrer-Policy") != "strict-origin-when-cross-origin" {
+		t.Error("invalid Referrer-Policy: ", h.Get("Referrer-Policy"))
+	}
+	if h.Get("Strict-Transport-Security") != "" {
+		t.Error("HSTS should not be set for plain HTTP requests")
+	}
+}
+
+func Test_Configured(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	Headers(Config{FrameOptions: "SAMEORIGIN", ReferrerPolicy: Disabled})(context.Background(), w, r)
+
+	if w.Header().Get("X-Frame-Options") != "SAMEORIGIN" {
+		t.Error("invalid X-Frame-Options: ", w.Header().Get("X-Frame-Options"))
+	}
+	if _, ok := w.Header()["Referrer-Policy"]; ok {
+		t.Error("Referrer-Policy should be disabled")
+	}
+}
+
+func Test_HSTS(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Forwarded-Proto", "https")
+	w := httptest.NewRecorder()
+	Headers(Config{})(context.Background(), w, r)
+
+	if w.Header().Get("Strict-Transport-Security") != "max-age=31536000; includeSubDomains" {
+		t.Error("invalid HSTS header: ", w.Header().Get("Strict-Transport-Security"))
+	}
+}
@@ -0,0 +1,95 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"time"
+
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/memcache"
+)
+
+// entity is how a session is kept within the datastore
+type entity struct {
+	Values  []byte `datastore:",noindex"`
+	Expires time.Time
+}
+
+// DatastoreStore keeps the sessions within the App Engine datastore
+type DatastoreStore struct {
+	// Kind is the kind of the session entities, defaulting to "Session"
+	Kind string
+
+	// Memcache keeps a copy of each session within memcache, which is read from
+	// ahead of the datastore
+	Memcache bool
+}
+
+func (s DatastoreStore) kind() string {
+	if s.Kind == "" {
+		return "Session"
+	}
+	return s.Kind
+}
+
+// Load returns the values of the session, reading from memcache first if enabled
+func (s DatastoreStore) Load(c context.Context, id string) (map[string]string, error) {
+	var e entity
+	cached := false
+	if s.Memcache {
+		if item, err := memcache.Get(c, s.kind()+":"+id); err == nil {
+			cached = gob.NewDecoder(bytes.NewReader(item.Value)).Decode(&e) == nil
+		}
+	}
+	if !cached {
+		err := datastore.Get(c, datastore.NewKey(c, s.kind(), id, 0, nil), &e)
+		if err == datastore.ErrNoSuchEntity {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if time.Now().After(e.Expires) {
+		return nil, nil
+	}
+
+	var values map[string]string
+	if err := gob.NewDecoder(bytes.NewReader(e.Values)).Decode(&values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Save stores the values of the session, along with a copy in memcache if enabled
+func (s DatastoreStore) Save(c context.Context, id string, values map[string]string, expires time.Time) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		return err
+	}
+	e := entity{Values: buf.Bytes(), Expires: expires}
+	if _, err := datastore.Put(c, datastore.NewKey(c, s.kind(), id, 0, nil), &e); err != nil {
+		return err
+	}
+
+	if s.Memcache {
+		var item bytes.Buffer
+		if gob.NewEncoder(&item).Encode(e) == nil {
+			memcache.Set(c, &memcache.Item{Key: s.kind() + ":" + id, Value: item.Bytes(), Expiration: time.Until(expires)})
+		}
+	}
+	return nil
+}
+
+// Delete removes the session from the datastore and memcache
+func (s DatastoreStore) Delete(c context.Context, id string) error {
+	if s.Memcache {
+		memcache.Delete(c, s.kind()+":"+id)
+	}
+	err := datastore.Delete(c, datastore.NewKey(c, s.kind(), id, 0, nil))
+	if err == datastore.ErrNoSuchEntity {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,256 @@
+package session
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+	"google.golang.org/appengine/log"
+)
+
+// Store keeps the values of each session
+type Store interface {
+	// Load returns the values of the session, which are nil if the session does not
+	// exist or has expired
+	Load(c context.Context, id string) (map[string]string, error)
+
+	// Save stores the values of the session until it expires
+	Save(c context.Context, id string, values map[string]string, expires time.Time) error
+
+	// Delete removes the session
+	Delete(c context.Context, id string) error
+}
+
+type config struct {
+	cookie   string
+	maxAge   time.Duration
+	sameSite http.SameSite
+	onError  func(context.Context, error)
+}
+
+// Option configures the session middleware
+type Option func(*config)
+
+// Cookie sets the name of the session cookie, which defaults to "session"
+func Cookie(name string) Option {
+	return func(cfg *config) {
+		cfg.cookie = name
+	}
+}
+
+// MaxAge sets how long a session lasts after it was last changed, and how long
+// the cookie lasts after the last request, which defaults to 24 hours
+func MaxAge(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.maxAge = d
+	}
+}
+
+// SameSite sets the SameSite attribute of the cookie, which defaults to
+// http.SameSiteLaxMode
+func SameSite(mode http.SameSite) Option {
+	return func(cfg *config) {
+		cfg.sameSite = mode
+	}
+}
+
+// OnError sets the function called when the session fails to be saved once the
+// request completes, replacing the default of logging the error to the App
+// Engine log of the request
+func OnError(fn func(context.Context, error)) Option {
+	return func(cfg *config) {
+		cfg.onError = fn
+	}
+}
+
+// logs the error of saving the session
+func logError(c context.Context, err error) {
+	log.Errorf(c, "session: saving the session failed: %v", err)
+}
+
+// Session holds the values of a client across requests. Its methods are safe for
+// concurrent use.
+type Session struct {
+	mu      sync.Mutex
+	id      string
+	oldID   string
+	values  map[string]string
+	changed bool
+
+	store Store
+	w     http.ResponseWriter
+	r     *http.Request
+	key   []byte
+	cfg   *config
+}
+
+// Get returns the value of the key, or an empty string if it is not set
+func (s *Session) Get(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+// Set sets the value of the key
+func (s *Session) Set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.changed = true
+}
+
+// Delete removes the key
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	s.changed = true
+}
+
+// Regenerate moves the session to a new ID, keeping its values, which should be
+// done when the privileges of the client change, such as on login, so an ID
+// obtained by an attacker beforehand is of no use. It must be called before the
+// response is written so the new cookie can be set.
+func (s *Session) Regenerate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.oldID == "" {
+		s.oldID = s.id
+	}
+	s.id = newID()
+	s.changed = true
+	s.setCookie()
+}
+
+// Save stores the session if it was changed, which otherwise happens once the
+// request completes
+func (s *Session) Save(c context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.oldID != "" {
+		if err := s.store.Delete(c, s.oldID); err != nil {
+			return err
+		}
+		s.oldID = ""
+	}
+	if !s.changed {
+		return nil
+	}
+	if err := s.store.Save(c, s.id, s.values, time.Now().Add(s.cfg.maxAge)); err != nil {
+		return err
+	}
+	s.changed = false
+	return nil
+}
+
+// Start returns a middleware that loads the session identified by the signed
+// session cookie, starting a new one if there is none, and stores it on the
+// context to be read with From. The session is saved to the store once the request
+// completes if it was changed, with a failure being passed to the OnError
+// function.
+//	q := quincy.New(session.Start(session.DatastoreStore{}, key))
+func Start(store Store, key []byte, opts ...Option) quincy.Middleware {
+	if len(key) == 0 {
+		panic("session: a key is required")
+	}
+	cfg := config{
+		cookie:   "session",
+		maxAge:   24 * time.Hour,
+		sameSite: http.SameSiteLaxMode,
+		onError:  logError,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		s := &Session{store: store, w: w, r: r, key: key, cfg: &cfg}
+		if cookie, err := r.Cookie(cfg.cookie); err == nil {
+			if id, ok := verify(key, cookie.Value); ok {
+				values, err := store.Load(c, id)
+				if err != nil {
+					return quincy.Fail(c, http.StatusInternalServerError, err)
+				}
+				if values != nil {
+					s.id, s.values = id, values
+				}
+			}
+		}
+		if s.id == "" {
+			s.id, s.values = newID(), map[string]string{}
+		}
+		// the cookie is set on each request so it expires after the last request
+		s.setCookie()
+
+		quincy.Defer(c, func() {
+			if err := s.Save(c); err != nil {
+				cfg.onError(c, err)
+			}
+		})
+		return quincy.WithValue(c, s)
+	}
+}
+
+// From returns the session started by Start, or nil if Start did not run
+//	session.From(c).Set("user", id)
+func From(c context.Context) *Session {
+	s, _ := quincy.Value[*Session](c)
+	return s
+}
+
+// sets the cookie holding the signed session ID, replacing one set earlier in the
+// request, such as by Regenerate, since a response should only set each cookie
+// once
+func (s *Session) setCookie() {
+	h := s.w.Header()
+	cookies := h["Set-Cookie"][:0]
+	for _, v := range h["Set-Cookie"] {
+		if !strings.HasPrefix(v, s.cfg.cookie+"=") {
+			cookies = append(cookies, v)
+		}
+	}
+	if len(cookies) == 0 {
+		h.Del("Set-Cookie")
+	} else {
+		h["Set-Cookie"] = cookies
+	}
+
+	http.SetCookie(s.w, &http.Cookie{
+		Name:     s.cfg.cookie,
+		Value:    s.id + "." + sign(s.key, s.id),
+		Path:     "/",
+		MaxAge:   int(s.cfg.maxAge / time.Second),
+		Secure:   s.r.TLS != nil || strings.EqualFold(s.r.Header.Get("X-Forwarded-Proto"), "https"),
+		HttpOnly: true,
+		SameSite: s.cfg.sameSite,
+	})
+}
+
+// returns a random 256 bit session ID
+func newID() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// returns the session ID held by the cookie value if its signature is valid
+func verify(key []byte, value string) (string, bool) {
+	id, sig, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(sign(key, id))) {
+		return "", false
+	}
+	return id, true
+}
+
+func sign(key []byte, id string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
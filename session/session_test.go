@@ -0,0 +1,186 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/testutil"
+)
+
+type stored struct {
+	values  map[string]string
+	expires time.Time
+}
+
+type memStore map[string]stored
+
+func (m memStore) Load(c context.Context, id string) (map[string]string, error) {
+	s, ok := m[id]
+	if !ok || time.Now().After(s.expires) {
+		return nil, nil
+	}
+	values := map[string]string{}
+	for k, v := range s.values {
+		values[k] = v
+	}
+	return values, nil
+}
+
+func (m memStore) Save(c context.Context, id string, values map[string]string, expires time.Time) error {
+	m[id] = stored{values, expires}
+	return nil
+}
+
+func (m memStore) Delete(c context.Context, id string) error {
+	delete(m, id)
+	return nil
+}
+
+var key = []byte("secret")
+
+// serves the request, returning the session cookie that was set
+func serve(store memStore, cookie *http.Cookie, fn quincy.HandlerFunc) *http.Cookie {
+	q := quincy.New(Start(store, key))
+	q.ContextFunc = testutil.StubContext
+
+	r := httptest.NewRequest("GET", "/", nil)
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	q.Then(fn)(w, r)
+
+	cookies := w.Result().Cookies()
+	if len(cookies) == 0 {
+		return nil
+	}
+	return cookies[len(cookies)-1]
+}
+
+func Test_Session(t *testing.T) {
+	store := memStore{}
+	cookie := serve(store, nil, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		From(c).Set("user", "42")
+	})
+
+	if cookie == nil || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Fatal("invalid session cookie: ", cookie)
+	}
+	if len(store) != 1 {
+		t.Fatal("session not saved")
+	}
+
+	var user string
+	serve(store, cookie, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		user = From(c).Get("user")
+	})
+	if user != "42" {
+		t.Error("session value not loaded: ", user)
+	}
+}
+
+func Test_Session_Unchanged(t *testing.T) {
+	store := memStore{}
+	serve(store, nil, func(c context.Context, w http.ResponseWriter, r *http.Request) {})
+
+	if len(store) != 0 {
+		t.Error("unchanged session should not be saved")
+	}
+}
+
+func Test_Session_Forged(t *testing.T) {
+	store := memStore{"known": {map[string]string{"user": "1"}, time.Now().Add(time.Hour)}}
+
+	var user string
+	serve(store, &http.Cookie{Name: "session", Value: "known.forged"}, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		user = From(c).Get("user")
+	})
+	if user != "" {
+		t.Error("session with a forged cookie should not be loaded")
+	}
+}
+
+func Test_Session_Expired(t *testing.T) {
+	store := memStore{"old": {map[string]string{"user": "1"}, time.Now().Add(-time.Hour)}}
+
+	var user string
+	serve(store, &http.Cookie{Name: "session", Value: "old." + sign(key, "old")}, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		user = From(c).Get("user")
+	})
+	if user != "" {
+		t.Error("expired session should not be loaded")
+	}
+}
+
+func Test_Session_Regenerate(t *testing.T) {
+	store := memStore{}
+	first := serve(store, nil, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		From(c).Set("user", "42")
+	})
+	second := serve(store, first, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		From(c).Regenerate()
+	})
+
+	if first.Value == second.Value {
+		t.Error("session ID not regenerated")
+	}
+	if len(store) != 1 {
+		t.Error("old session not deleted")
+	}
+
+	var user string
+	serve(store, second, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		user = From(c).Get("user")
+	})
+	if user != "42" {
+		t.Error("values not kept across regeneration: ", user)
+	}
+}
+
+func Test_Session_RegenerateCookie(t *testing.T) {
+	q := quincy.New(Start(memStore{}, key))
+	q.ContextFunc = testutil.StubContext
+
+	w := httptest.NewRecorder()
+	q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark"})
+		From(c).Regenerate()
+	})(w, httptest.NewRequest("GET", "/", nil))
+
+	var names []string
+	for _, cookie := range w.Result().Cookies() {
+		names = append(names, cookie.Name)
+	}
+	if len(names) != 2 || names[0] != "theme" || names[1] != "session" {
+		t.Error("the session cookie should be set once: ", names)
+	}
+}
+
+type failingStore struct {
+	memStore
+}
+
+func (failingStore) Save(c context.Context, id string, values map[string]string, expires time.Time) error {
+	return errors.New("unavailable")
+}
+
+func Test_Session_SaveError(t *testing.T) {
+	var saveErr error
+	q := quincy.New(Start(failingStore{memStore{}}, key, OnError(func(c context.Context, err error) {
+		saveErr = err
+	})))
+	q.ContextFunc = testutil.StubContext
+
+	q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		From(c).Set("user", "42")
+	})(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if saveErr == nil || saveErr.Error() != "unavailable" {
+		t.Error("the save error should be passed to OnError: ", saveErr)
+	}
+}
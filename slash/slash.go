@@ -0,0 +1,79 @@
+package slash
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// Config defines how a path with a trailing slash is normalized
+type Config struct {
+	// Redirect sends the client to the path without the trailing slash instead of
+	// rewriting the request in place
+	Redirect bool
+}
+
+// Strip returns a middleware that removes the trailing slash from the request path
+// before the rest of the chain runs
+//	q := quincy.New(slash.Strip())
+func Strip() quincy.Middleware {
+	return Normalize(Config{})
+}
+
+// Redirect returns a middleware that permanently redirects requests with a
+// trailing slash to the path without it, ending the chain
+//	q := quincy.New(slash.Redirect())
+func Redirect() quincy.Middleware {
+	return Normalize(Config{Redirect: true})
+}
+
+// Normalize returns a middleware that removes the trailing slash from the request
+// path, or redirects to the path without it, as configured. The root path is left
+// as is.
+func Normalize(cfg Config) quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		p := r.URL.Path
+		if len(p) < 2 || !strings.HasSuffix(p, "/") {
+			return c
+		}
+		p = trim(p)
+
+		if !cfg.Redirect {
+			r.URL.Path = p
+			if r.URL.RawPath != "" {
+				r.URL.RawPath = trim(r.URL.RawPath)
+			}
+			return c
+		}
+
+		u := *r.URL
+		u.Path = p
+		if u.RawPath != "" {
+			u.RawPath = trim(r.URL.RawPath)
+		}
+		loc := u.EscapedPath()
+		if u.RawQuery != "" {
+			loc += "?" + u.RawQuery
+		}
+
+		// a 301 would turn other methods into a GET, so 308 is used to keep them
+		status := http.StatusMovedPermanently
+		if r.Method != "GET" && r.Method != "HEAD" {
+			status = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, loc, status)
+		return quincy.Stop(c)
+	}
+}
+
+// removes the trailing slashes from the path, collapsing any leading slashes so
+// a redirect can't become a protocol relative URL to another host
+func trim(p string) string {
+	p = strings.TrimRight(p, "/")
+	if p == "" || strings.HasPrefix(p, "//") {
+		p = "/" + strings.TrimLeft(p, "/")
+	}
+	return p
+}
@@ -0,0 +1,64 @@
+package slash
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Strip(t *testing.T) {
+	tests := map[string]string{
+		"/":       "/",
+		"/foo":    "/foo",
+		"/foo/":   "/foo",
+		"/foo//":  "/foo",
+		"/a/b/":   "/a/b",
+		"//evil/": "/evil",
+	}
+
+	for path, expected := range tests {
+		r := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		c := Strip()(context.Background(), w, r)
+
+		if r.URL.Path != expected {
+			t.Errorf("%s: expected %s, got %s", path, expected, r.URL.Path)
+		}
+		if c.Err() != nil {
+			t.Errorf("%s: chain should continue", path)
+		}
+	}
+}
+
+func Test_Redirect(t *testing.T) {
+	r := httptest.NewRequest("GET", "/foo/?a=1", nil)
+	w := httptest.NewRecorder()
+	Redirect()(context.Background(), w, r)
+
+	if w.Code != 301 {
+		t.Error("expected 301, got ", w.Code)
+	}
+	if loc := w.Header().Get("Location"); loc != "/foo?a=1" {
+		t.Error("invalid location: ", loc)
+	}
+}
+
+func Test_Redirect_Post(t *testing.T) {
+	r := httptest.NewRequest("POST", "/foo/", nil)
+	w := httptest.NewRecorder()
+	Redirect()(context.Background(), w, r)
+
+	if w.Code != 308 {
+		t.Error("expected 308, got ", w.Code)
+	}
+}
+
+func Test_Redirect_Root(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	Redirect()(context.Background(), w, r)
+
+	if w.Code != 200 || w.Header().Get("Location") != "" {
+		t.Error("root should not be redirected")
+	}
+}
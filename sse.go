@@ -0,0 +1,80 @@
+package quincy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrNotFlusher is returned by SSEWriter.Send when the ResponseWriter can't flush
+// each event to the client
+var ErrNotFlusher = errors.New("quincy: response writer does not support flushing")
+
+// SSEWriter writes Server-Sent Events, flushing each one to the client as it is
+// sent
+//	sse := quincy.NewSSEWriter(w)
+//	for msg := range messages {
+//		if err := sse.Send("message", msg); err != nil {
+//			return
+//		}
+//	}
+type SSEWriter struct {
+	mu sync.Mutex
+	w  http.ResponseWriter
+}
+
+// NewSSEWriter sets the event stream headers on w and returns a writer for sending
+// events with it
+func NewSSEWriter(w http.ResponseWriter) *SSEWriter {
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Del("Content-Length")
+	return &SSEWriter{w: w}
+}
+
+// Send writes the event, with each line of the data as a separate data field, and
+// flushes it to the client. The event field is left out if event is empty, in
+// which case clients treat it as a message event.
+func (s *SSEWriter) Send(event, data string) error {
+	if !canFlush(s.w) {
+		return ErrNotFlusher
+	}
+
+	var b strings.Builder
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", strings.NewReplacer("\r", "", "\n", "").Replace(event))
+	}
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write([]byte(b.String())); err != nil {
+		return err
+	}
+	s.w.(http.Flusher).Flush()
+	return nil
+}
+
+// reports whether w can flush to the client, looking through the writers that
+// wrap another, since they implement Flush whether or not the writer they wrap
+// does
+func canFlush(w http.ResponseWriter) bool {
+	for {
+		if _, ok := w.(http.Flusher); !ok {
+			return false
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return true
+		}
+		w = u.Unwrap()
+	}
+}
@@ -0,0 +1,81 @@
+package quincy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_SSEWriter(t *testing.T) {
+	q := New()
+	q.ContextFunc = background
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		sse := NewSSEWriter(w)
+		if err := sse.Send("update", "foo\nbar"); err != nil {
+			t.Error("unexpected error: ", err)
+		}
+		if err := sse.Send("", "baz"); err != nil {
+			t.Error("unexpected error: ", err)
+		}
+	})
+
+	w := httptest.NewRecorder()
+	fn(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Error("invalid content type: ", w.Header().Get("Content-Type"))
+	}
+	if w.Header().Get("Cache-Control") != "no-cache" {
+		t.Error("invalid cache control: ", w.Header().Get("Cache-Control"))
+	}
+	if !w.Flushed {
+		t.Error("events were not flushed")
+	}
+	expected := "event: update\ndata: foo\ndata: bar\n\ndata: baz\n\n"
+	if w.Body.String() != expected {
+		t.Errorf("invalid body: %q", w.Body.String())
+	}
+}
+
+// plainWriter is a ResponseWriter that doesn't support flushing
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+func Test_SSEWriterNotFlusher(t *testing.T) {
+	rec := httptest.NewRecorder()
+	q := New()
+	q.ContextFunc = background
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		if err := NewSSEWriter(w).Send("update", "foo"); err != ErrNotFlusher {
+			t.Error("invalid error: ", err)
+		}
+	})
+
+	fn(plainWriter{rec}, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Body.Len() != 0 {
+		t.Error("nothing should be written: ", rec.Body.String())
+	}
+}
+
+func Test_SSEWriterNotFlusherWrapped(t *testing.T) {
+	rec := httptest.NewRecorder()
+	wrap := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return WithWriter(c, headerWriter{Wrapper{ResponseWriter: w}})
+	}
+	q := New(wrap)
+	q.ContextFunc = background
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		if err := NewSSEWriter(w).Send("update", "foo"); err != ErrNotFlusher {
+			t.Error("invalid error: ", err)
+		}
+	})
+
+	fn(plainWriter{rec}, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Body.Len() != 0 {
+		t.Error("nothing should be written: ", rec.Body.String())
+	}
+}
@@ -0,0 +1,114 @@
+package quincy
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/appengine"
+)
+
+type stdKey struct{}
+
+// stdResult records whether a standard middleware called its next handler and
+// the request it was called with
+type stdResult struct {
+	r *http.Request
+}
+
+// FromStd adapts a standard func(http.Handler) http.Handler middleware into a
+// Middleware. The request passed to the standard middleware carries the chain
+// context, and if it calls the next handler the chain continues with the request
+// it was given, so any values it adds to the context and any changes it makes to
+// the request, such as to RemoteAddr or the Body, are available downstream.
+// If the next handler is not called the standard middleware is assumed to have
+// written the response and the chain is aborted. The ResponseWriter passed to
+// the next handler is not carried on, so wrappers of it only apply within the
+// standard middleware itself.
+//	q := quincy.New(quincy.FromStd(handlers.ProxyHeaders))
+func FromStd(m func(http.Handler) http.Handler) Middleware {
+	h := m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if res, ok := r.Context().Value(stdKey{}).(*stdResult); ok {
+			res.r = r
+		}
+	}))
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		res := &stdResult{}
+		h.ServeHTTP(w, r.WithContext(context.WithValue(c, stdKey{}, res)))
+		if res.r == nil {
+			return abort(c)
+		}
+		*r = *res.r
+		return res.r.Context()
+	}
+}
+
+// FromNegroni adapts a negroni style middleware, which is given the next handler
+// to call, into a Middleware. As with FromStd the chain continues with the request
+// passed to next, and is aborted if next is not called.
+//	q := quincy.New(quincy.FromNegroni(negroni.NewRecovery().ServeHTTP))
+func FromNegroni(h func(http.ResponseWriter, *http.Request, http.HandlerFunc)) Middleware {
+	return FromStd(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h(w, r, next.ServeHTTP)
+		})
+	})
+}
+
+// FromAlice returns a chain made up of the standard middleware constructors of an
+// alice chain, each adapted with FromStd, in the same order so the first
+// constructor runs first. The work a constructor does after calling next runs
+// before the rest of the chain rather than around it.
+//	q := quincy.FromAlice(handlers.ProxyHeaders, nosurf.NewPure)
+func FromAlice(constructors ...func(http.Handler) http.Handler) *Q {
+	fns := make([]Middleware, len(constructors))
+	for i, fn := range constructors {
+		fns[i] = FromStd(fn)
+	}
+	return New(fns...)
+}
+
+// Std returns the chain as a standard func(http.Handler) http.Handler middleware.
+// The context is created with the ContextFunc of the chain and, if no middleware
+// aborts the chain, next is called with the resulting context set on the request.
+//	r := chi.NewRouter()
+//	r.Use(q.Std())
+func (q *Q) Std() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return q.handler(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(c))
+		})
+	}
+}
+
+// Adapt converts a HandlerFunc into a standard http.HandlerFunc for endpoints that
+// require no middleware, creating the context with appengine.NewContext
+//	http.HandleFunc("/", quincy.Adapt(handleRoot))
+func Adapt(fn HandlerFunc) http.HandlerFunc {
+	return AdaptWith(appengine.NewContext, fn)
+}
+
+// AdaptWith converts a HandlerFunc into a standard http.HandlerFunc, creating the
+// context with the ctxFn provided
+func AdaptWith(ctxFn func(*http.Request) context.Context, fn HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fn(ctxFn(r), w, r)
+	}
+}
+
+// httpHandler runs a standard http.Handler as the final Handler of a chain
+type httpHandler struct {
+	h http.Handler
+}
+
+func (h httpHandler) ServeHTTP(c context.Context, w http.ResponseWriter, r *http.Request) {
+	h.h.ServeHTTP(w, r.WithContext(c))
+}
+
+// WrapHTTP converts a standard http.Handler into a Handler, allowing it to end a
+// chain. The chain context is set on the request, so the handler reads it with
+// r.Context().
+//	http.Handle("/static/", q.Handle(quincy.WrapHTTP(http.FileServer(http.Dir("static")))))
+func WrapHTTP(h http.Handler) Handler {
+	return httpHandler{h}
+}
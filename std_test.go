@@ -0,0 +1,199 @@
+package quincy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_FromStd(t *testing.T) {
+	std := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("foo", "bar")
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "key", "foobar")))
+		})
+	}
+
+	var calls []string
+	q := New(FromStd(std), record("next", &calls))
+	w := httptest.NewRecorder()
+	c := q.Run(context.Background(), w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Header().Get("foo") != "bar" {
+		t.Error("Header not set")
+	}
+	if c.Err() != nil {
+		t.Error("chain should not be aborted")
+	}
+	if c.Value("key") != "foobar" {
+		t.Error("request context value not carried into the chain")
+	}
+	if len(calls) != 1 {
+		t.Error("chain did not continue")
+	}
+}
+
+func Test_FromStdRequest(t *testing.T) {
+	std := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(r.Context())
+			r.RemoteAddr = "203.0.113.1:80"
+			r.Body = io.NopCloser(strings.NewReader("wrapped"))
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	var remoteAddr, body string
+	q := New(FromStd(std))
+	q.ContextFunc = background
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		remoteAddr = r.RemoteAddr
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	})
+	fn(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader("foo")))
+
+	if remoteAddr != "203.0.113.1:80" {
+		t.Error("RemoteAddr not carried into the chain: ", remoteAddr)
+	}
+	if body != "wrapped" {
+		t.Error("Body not carried into the chain: ", body)
+	}
+}
+
+func Test_FromStdShortCircuit(t *testing.T) {
+	std := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+	}
+
+	var calls []string
+	q := New(FromStd(std), record("next", &calls))
+	w := httptest.NewRecorder()
+	c := q.Run(context.Background(), w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusForbidden {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if c.Err() == nil {
+		t.Error("chain should be aborted")
+	}
+	if len(calls) != 0 {
+		t.Error("chain should not continue")
+	}
+}
+
+func Test_FromNegroni(t *testing.T) {
+	negroni := func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), "key", "foobar")))
+	}
+
+	var calls []string
+	q := New(FromNegroni(negroni), record("next", &calls))
+
+	w := httptest.NewRecorder()
+	c := q.Run(context.Background(), w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusUnauthorized || c.Err() == nil || len(calls) != 0 {
+		t.Error("chain should be aborted when next is not called")
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "token")
+	c = q.Run(context.Background(), httptest.NewRecorder(), r)
+	if c.Err() != nil || len(calls) != 1 {
+		t.Error("chain should continue when next is called")
+	}
+	if c.Value("key") != "foobar" {
+		t.Error("request context value not carried into the chain")
+	}
+}
+
+func Test_FromAlice(t *testing.T) {
+	var calls []string
+	constructor := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	q := FromAlice(constructor("first"), constructor("second"))
+	q.ContextFunc = background
+	q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	})(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if strings.Join(calls, ",") != "first,second,handler" {
+		t.Error("invalid call order: ", calls)
+	}
+}
+
+func Test_Std(t *testing.T) {
+	var calls []string
+	q := New(record("a", &calls), func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return context.WithValue(c, "key", "foobar")
+	})
+	q.ContextFunc = background
+
+	h := q.Std()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+		if r.Context().Value("key") != "foobar" {
+			t.Error("chain context not set on the request")
+		}
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if len(calls) != 2 || calls[0] != "a" {
+		t.Error("invalid call order: ", calls)
+	}
+}
+
+func Test_AdaptWith(t *testing.T) {
+	var called bool
+	fn := AdaptWith(background, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		called = true
+		if c == nil {
+			t.Error("context is nil")
+		}
+	})
+	fn(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if !called {
+		t.Error("handler was not called")
+	}
+}
+
+func Test_WrapHTTP(t *testing.T) {
+	var calls []string
+	q := New(record("a", &calls), func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return context.WithValue(c, "key", "foobar")
+	})
+	q.ContextFunc = background
+
+	h := q.Handle(WrapHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+		if r.Context().Value("key") != "foobar" {
+			t.Error("chain context not set on the request")
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if strings.Join(calls, ",") != "a,handler" {
+		t.Error("invalid call order: ", calls)
+	}
+	if w.Code != http.StatusAccepted {
+		t.Error("Invalid response status: ", w.Code)
+	}
+}
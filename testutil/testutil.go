@@ -0,0 +1,59 @@
+package testutil
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"google.golang.org/appengine"
+)
+
+type config struct {
+	ctxFn func(*http.Request) context.Context
+}
+
+// Option configures the values built by NewTestRequest
+type Option func(*config)
+
+// Stub creates the context from the request context rather than with
+// appengine.NewContext, for testing middleware that don't call App Engine
+// services, which then don't need an aetest instance
+//	c, r, w := testutil.NewTestRequest("GET", "/", nil, testutil.Stub())
+func Stub() Option {
+	return func(cfg *config) {
+		cfg.ctxFn = StubContext
+	}
+}
+
+// StubContext returns the request context, for use as the ContextFunc of a chain
+// whose middleware don't call App Engine services
+//	q.ContextFunc = testutil.StubContext
+func StubContext(r *http.Request) context.Context {
+	return r.Context()
+}
+
+// ContextFunc creates the context with fn, such as the ContextFunc of the chain
+// being tested
+//	c, r, w := testutil.NewTestRequest("GET", "/", nil, testutil.ContextFunc(q.ContextFunc))
+func ContextFunc(fn func(*http.Request) context.Context) Option {
+	return func(cfg *config) {
+		cfg.ctxFn = fn
+	}
+}
+
+// NewTestRequest returns a request for the method and path, the context created
+// for it and a recorder for the response, ready to be passed to q.Run. The context
+// is created with appengine.NewContext unless the Stub or ContextFunc option is
+// provided.
+//	c, r, w := testutil.NewTestRequest("POST", "/users", strings.NewReader(body), testutil.Stub())
+//	c = q.Run(c, w, r)
+func NewTestRequest(method, path string, body io.Reader, opts ...Option) (context.Context, *http.Request, *httptest.ResponseRecorder) {
+	cfg := config{ctxFn: appengine.NewContext}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	r := httptest.NewRequest(method, path, body)
+	return cfg.ctxFn(r), r, httptest.NewRecorder()
+}
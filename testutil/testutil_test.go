@@ -0,0 +1,50 @@
+package testutil
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/chrisolsen/quincy"
+)
+
+type token string
+
+func Test_NewTestRequest(t *testing.T) {
+	c, r, w := NewTestRequest("POST", "/users", strings.NewReader("foo"), Stub())
+	if c == nil || r == nil || w == nil {
+		t.Error("expected non-nil values")
+		return
+	}
+	if r.Method != "POST" || r.URL.Path != "/users" {
+		t.Error("invalid request: ", r.Method, r.URL.Path)
+	}
+	if b, _ := io.ReadAll(r.Body); string(b) != "foo" {
+		t.Error("invalid body: ", string(b))
+	}
+
+	q := quincy.New(func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		w.WriteHeader(http.StatusCreated)
+		return quincy.WithValue(c, token("foobar"))
+	})
+	c = q.Run(c, w, r)
+
+	if v, _ := quincy.Value[token](c); v != "foobar" {
+		t.Error("context should be usable by the chain")
+	}
+	if w.Code != http.StatusCreated {
+		t.Error("invalid response status: ", w.Code)
+	}
+}
+
+func Test_NewTestRequestContextFunc(t *testing.T) {
+	c, _, _ := NewTestRequest("GET", "/", nil, ContextFunc(func(r *http.Request) context.Context {
+		return quincy.WithValue(r.Context(), token("foobar"))
+	}))
+
+	if v, _ := quincy.Value[token](c); v != "foobar" {
+		t.Error("context should be created by the ContextFunc")
+	}
+}
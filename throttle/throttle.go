@@ -0,0 +1,82 @@
+package throttle
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+)
+
+type config struct {
+	backlog int
+	timeout time.Duration
+}
+
+// Option configures the throttle middleware
+type Option func(*config)
+
+// Backlog sets the number of requests that wait for a slot once all of them are
+// in use, with any further requests being rejected. The default is no backlog.
+func Backlog(n int) Option {
+	return func(cfg *config) {
+		cfg.backlog = n
+	}
+}
+
+// Timeout sets how long a request waits in the backlog before being rejected. By
+// default requests wait until a slot is free or the client goes away.
+func Timeout(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.timeout = d
+	}
+}
+
+// Limit returns a middleware that caps the number of requests in flight at n,
+// rejecting requests with a 503 once the backlog is full or they time out while
+// waiting. A slot is held until the request completes, so the middleware only
+// takes effect with Then and Handle.
+//	q := quincy.New(throttle.Limit(100, throttle.Backlog(50), throttle.Timeout(time.Second)))
+func Limit(n int, opts ...Option) quincy.Middleware {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	slots := make(chan struct{}, n)
+	queue := make(chan struct{}, n+cfg.backlog)
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		select {
+		case queue <- struct{}{}:
+		default:
+			return quincy.Abort(c, w, http.StatusServiceUnavailable)
+		}
+
+		var expired <-chan time.Time
+		if cfg.timeout > 0 {
+			timer := time.NewTimer(cfg.timeout)
+			defer timer.Stop()
+			expired = timer.C
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-c.Done():
+			<-queue
+			return c
+		case <-expired:
+			<-queue
+			return quincy.Abort(c, w, http.StatusServiceUnavailable)
+		}
+
+		release := func() {
+			<-slots
+			<-queue
+		}
+		if !quincy.Defer(c, release) {
+			release()
+		}
+		return c
+	}
+}
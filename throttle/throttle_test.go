@@ -0,0 +1,108 @@
+package throttle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/testutil"
+)
+
+func handler(mw quincy.Middleware, fn quincy.HandlerFunc) func(http.ResponseWriter, *http.Request) {
+	q := quincy.New(mw)
+	q.ContextFunc = testutil.StubContext
+	return q.Then(fn)
+}
+
+func Test_Limit(t *testing.T) {
+	block := make(chan struct{})
+	h := handler(Limit(2, Backlog(1)), func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		<-block
+	})
+
+	codes := make(chan int, 5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			w := httptest.NewRecorder()
+			h(w, httptest.NewRequest("GET", "/", nil))
+			codes <- w.Code
+		}()
+	}
+
+	// nothing completes while the handlers are blocked other than the requests
+	// rejected once the slots and backlog are full, so two of them must have been
+	// answered before the rest are let through
+	for i := 0; i < 2; i++ {
+		select {
+		case code := <-codes:
+			if code != 503 {
+				t.Fatal("expected the request to be rejected, got ", code)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected 2 requests to be rejected")
+		}
+	}
+	close(block)
+
+	for i := 0; i < 3; i++ {
+		if code := <-codes; code != 200 {
+			t.Error("expected the request to be served, got ", code)
+		}
+	}
+}
+
+func Test_Limit_Timeout(t *testing.T) {
+	block := make(chan struct{})
+	started := make(chan struct{})
+	h := handler(Limit(1, Backlog(1), Timeout(10*time.Millisecond)), func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-block
+	})
+	defer close(block)
+
+	go h(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	<-started
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 503 {
+		t.Error("expected queued request to time out with 503, got ", w.Code)
+	}
+}
+
+func Test_Limit_Cancel(t *testing.T) {
+	block := make(chan struct{})
+	started := make(chan struct{})
+	calls := 0
+	h := handler(Limit(1, Backlog(1)), func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			close(started)
+			<-block
+		}
+	})
+
+	go h(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	<-started
+
+	// a queued request that is cancelled gives up its place in the backlog
+	c, cancel := context.WithCancel(context.Background())
+	cancel()
+	h(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil).WithContext(c))
+
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		h(w, httptest.NewRequest("GET", "/", nil))
+		close(done)
+	}()
+	close(block)
+	<-done
+
+	if w.Code != 200 {
+		t.Error("backlog slot not released by the cancelled request, got ", w.Code)
+	}
+}
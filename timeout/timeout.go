@@ -0,0 +1,183 @@
+package timeout
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// Option configures the timeout middleware
+type Option func(*config)
+
+type config struct {
+	blockLateWrites bool
+}
+
+// BlockLateWrites wraps the ResponseWriter passed to the rest of the chain so that
+// once the context is done, writes to the response fail with
+// http.ErrHandlerTimeout and status codes are ignored. This keeps a handler that
+// carries on past the deadline from sending a partial response. If the deadline
+// expired before anything was written, the timeout status of the chain, 504 by
+// default, is written once the handler returns.
+//	q := quincy.New(timeout.After(10*time.Second, timeout.BlockLateWrites()))
+func BlockLateWrites() Option {
+	return func(cfg *config) {
+		cfg.blockLateWrites = true
+	}
+}
+
+// After returns a middleware that sets a deadline of d on the context passed to
+// the rest of the chain. A deadline that expires between middleware stops the
+// chain, while one that expires within the final handler is left for the handler
+// to act on by checking c.Done(). The context is released once the request
+// completes.
+//	q := quincy.New(timeout.After(10 * time.Second))
+func After(d time.Duration, opts ...Option) quincy.Middleware {
+	cfg := newConfig(opts)
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		c, cancel := context.WithTimeout(c, d)
+		quincy.Defer(c, cancel)
+		return cfg.wrap(c, w)
+	}
+}
+
+// the units of a grpc-timeout header value
+var grpcUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+// FromHeader returns a middleware that sets a deadline on the context from the
+// time the client is willing to wait, as given by the header, clamped to max. The
+// value may be a duration such as "1.5s", a number of seconds, or, for the
+// grpc-timeout header, a gRPC timeout such as "200m". A missing or invalid value
+// falls back to max, with a max of zero setting no deadline in that case. The
+// context is released once the request completes.
+//	q := quincy.New(timeout.FromHeader("X-Request-Timeout", 30*time.Second))
+func FromHeader(header string, max time.Duration, opts ...Option) quincy.Middleware {
+	cfg := newConfig(opts)
+	grpc := strings.EqualFold(header, "grpc-timeout")
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		d, ok := parse(r.Header.Get(header), grpc)
+		if !ok || (max > 0 && d > max) {
+			d = max
+		}
+		if d <= 0 {
+			return c
+		}
+
+		c, cancel := context.WithTimeout(c, d)
+		quincy.Defer(c, cancel)
+		return cfg.wrap(c, w)
+	}
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// replaces the writer of the chain with one that blocks writes once c is done,
+// if requested
+func (cfg *config) wrap(c context.Context, w http.ResponseWriter) context.Context {
+	if !cfg.blockLateWrites || w == nil {
+		return c
+	}
+	dw := &deadlineWriter{Wrapper: quincy.Wrapper{ResponseWriter: w}, c: c}
+	quincy.Defer(c, dw.finish)
+	return quincy.WithWriter(c, dw)
+}
+
+// parses a timeout header value, which must be positive
+func parse(v string, grpc bool) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+
+	if grpc {
+		unit, ok := grpcUnits[v[len(v)-1]]
+		n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+		if !ok || err != nil || n <= 0 || len(v) > 9 {
+			return 0, false
+		}
+		return time.Duration(n) * unit, true
+	}
+
+	if d, err := time.ParseDuration(v); err == nil {
+		return d, d > 0
+	}
+	secs, err := strconv.ParseFloat(v, 64)
+	if err != nil || secs <= 0 || secs > float64(math.MaxInt64/int64(time.Second)) {
+		return 0, false
+	}
+	return time.Duration(secs * float64(time.Second)), true
+}
+
+// deadlineWriter drops the writes made once its context is done
+type deadlineWriter struct {
+	quincy.Wrapper
+	c       context.Context
+	written bool
+}
+
+// writes the timeout status if the deadline expired before the handler wrote
+// anything. A chain stopped by the deadline already had its status written, and
+// nothing is written for a client that went away.
+func (dw *deadlineWriter) finish() {
+	if dw.written || quincy.Err(dw.c) != nil || dw.c.Err() != context.DeadlineExceeded {
+		return
+	}
+	if rec, ok := dw.ResponseWriter.(*quincy.StatusRecorder); ok && rec.Status() != 0 {
+		return
+	}
+	dw.ResponseWriter.WriteHeader(quincy.TimeoutStatus(dw.c))
+}
+
+// reports whether the context is done
+func (dw *deadlineWriter) expired() bool {
+	select {
+	case <-dw.c.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+func (dw *deadlineWriter) WriteHeader(status int) {
+	if dw.expired() {
+		return
+	}
+	dw.written = true
+	dw.ResponseWriter.WriteHeader(status)
+}
+
+func (dw *deadlineWriter) Write(b []byte) (int, error) {
+	if dw.expired() {
+		return 0, http.ErrHandlerTimeout
+	}
+	dw.written = true
+	return dw.ResponseWriter.Write(b)
+}
+
+// Flush sends the response written so far, if the underlying writer supports it
+// and the context is not done
+func (dw *deadlineWriter) Flush() {
+	if !dw.expired() {
+		dw.Wrapper.Flush()
+	}
+}
+
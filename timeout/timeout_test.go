@@ -0,0 +1,152 @@
+package timeout
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/testutil"
+)
+
+func Test_After(t *testing.T) {
+	slow := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		time.Sleep(20 * time.Millisecond)
+		return c
+	}
+	next := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		t.Error("middleware after the deadline should not be called")
+		return c
+	}
+
+	q := quincy.New(After(time.Millisecond), slow, next)
+	q.ContextFunc = testutil.StubContext
+
+	var hookErr error
+	q.OnError(func(c context.Context, w http.ResponseWriter, r *http.Request, err error) {
+		hookErr = err
+	})
+
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called")
+	})
+	fn(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if hookErr != context.DeadlineExceeded {
+		t.Error("expected the deadline to be exceeded: ", hookErr)
+	}
+}
+
+func Test_AfterDeadline(t *testing.T) {
+	c := After(time.Minute)(context.Background(), nil, nil)
+
+	d, ok := c.Deadline()
+	if !ok {
+		t.Error("deadline not set")
+		return
+	}
+	if time.Until(d) > time.Minute {
+		t.Error("invalid deadline: ", d)
+	}
+}
+
+func Test_FromHeader(t *testing.T) {
+	tests := []struct {
+		header, value string
+		expected      time.Duration
+	}{
+		{"X-Request-Timeout", "2s", 2 * time.Second},
+		{"X-Request-Timeout", "1.5", 1500 * time.Millisecond},
+		{"X-Request-Timeout", "1m", 10 * time.Second},
+		{"X-Request-Timeout", "", 10 * time.Second},
+		{"X-Request-Timeout", "soon", 10 * time.Second},
+		{"X-Request-Timeout", "-1s", 10 * time.Second},
+		{"grpc-timeout", "200m", 200 * time.Millisecond},
+		{"grpc-timeout", "3S", 3 * time.Second},
+		{"grpc-timeout", "3x", 10 * time.Second},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.value != "" {
+			r.Header.Set(test.header, test.value)
+		}
+
+		c := FromHeader(test.header, 10*time.Second)(context.Background(), nil, r)
+		deadline, ok := c.Deadline()
+		if !ok {
+			t.Errorf("%s %q: no deadline set", test.header, test.value)
+			continue
+		}
+		if d := time.Until(deadline); d < test.expected-time.Second/10 || d > test.expected {
+			t.Errorf("%s %q: expected a deadline of %v, got %v", test.header, test.value, test.expected, d)
+		}
+	}
+}
+
+func Test_FromHeader_NoMax(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	c := FromHeader("X-Request-Timeout", 0)(context.Background(), nil, r)
+	if _, ok := c.Deadline(); ok {
+		t.Error("no deadline should be set without a header or max")
+	}
+
+	r.Header.Set("X-Request-Timeout", "5s")
+	c = FromHeader("X-Request-Timeout", 0)(context.Background(), nil, r)
+	if _, ok := c.Deadline(); !ok {
+		t.Error("the header deadline should be set without a max")
+	}
+}
+
+func Test_BlockLateWrites(t *testing.T) {
+	tests := []struct {
+		timeoutStatus, expected int
+	}{
+		{0, http.StatusGatewayTimeout},
+		{http.StatusRequestTimeout, http.StatusRequestTimeout},
+	}
+
+	for _, test := range tests {
+		q := quincy.New(After(10*time.Millisecond, BlockLateWrites()))
+		q.ContextFunc = testutil.StubContext
+		q.TimeoutStatus = test.timeoutStatus
+
+		var lateErr error
+		fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+			<-c.Done()
+			w.WriteHeader(http.StatusTeapot)
+			_, lateErr = w.Write([]byte("late"))
+		})
+		w := httptest.NewRecorder()
+		fn(w, httptest.NewRequest("GET", "/", nil))
+
+		if w.Code != test.expected {
+			t.Errorf("expected %d, got %d", test.expected, w.Code)
+		}
+		if w.Body.Len() != 0 {
+			t.Error("writes after the deadline should be dropped: ", w.Body.String())
+		}
+		if lateErr != http.ErrHandlerTimeout {
+			t.Error("invalid error: ", lateErr)
+		}
+	}
+}
+
+func Test_BlockLateWritesAfterWrite(t *testing.T) {
+	q := quincy.New(After(10*time.Millisecond, BlockLateWrites()))
+	q.ContextFunc = testutil.StubContext
+
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("foo"))
+		<-c.Done()
+		w.Write([]byte("bar"))
+	})
+	w := httptest.NewRecorder()
+	fn(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusOK || w.Body.String() != "foo" {
+		t.Error("the response started before the deadline should be kept: ", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,48 @@
+package quincy
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type startKey struct{}
+
+// StartTimer returns a middleware that records the current time as the start of
+// the request, for chains that are run with Run. Then and Handle record the start
+// before the first middleware runs, in which case StartTimer leaves it unchanged.
+//	q := quincy.New(quincy.StartTimer(), auth)
+//	c = q.Run(c, w, r)
+func StartTimer() Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		if st := stateFrom(c); st != nil {
+			return c
+		}
+		return context.WithValue(c, startKey{}, time.Now())
+	}
+}
+
+// StartTime returns the time the request started, as recorded by Then, Handle or
+// StartTimer, or the zero time if it wasn't recorded. Middleware should use it
+// rather than their own start time so the durations they report agree.
+//	log.Infof(c, "started at %v", quincy.StartTime(c))
+func StartTime(c context.Context) time.Time {
+	if st := stateFrom(c); st != nil {
+		return st.start
+	}
+	start, _ := c.Value(startKey{}).(time.Time)
+	return start
+}
+
+// Elapsed returns the time since the request started, or zero if the start time
+// wasn't recorded
+//	quincy.Defer(c, func() {
+//		log.Infof(c, "%s took %v", r.URL.Path, quincy.Elapsed(c))
+//	})
+func Elapsed(c context.Context) time.Duration {
+	start := StartTime(c)
+	if start.IsZero() {
+		return 0
+	}
+	return time.Since(start)
+}
@@ -0,0 +1,57 @@
+package quincy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_StartTime(t *testing.T) {
+	var starts []time.Time
+	var elapsed []time.Duration
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		starts = append(starts, StartTime(c))
+		elapsed = append(elapsed, Elapsed(c))
+		time.Sleep(time.Millisecond)
+		return c
+	}
+
+	before := time.Now()
+	q := New(mw, StartTimer(), mw)
+	q.ContextFunc = background
+	q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		starts = append(starts, StartTime(c))
+		elapsed = append(elapsed, Elapsed(c))
+	})(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if starts[0].Before(before) {
+		t.Error("start recorded before the request: ", starts[0])
+	}
+	for i := 1; i < len(starts); i++ {
+		if !starts[i].Equal(starts[0]) {
+			t.Error("start times should be consistent across the chain: ", starts)
+		}
+		if elapsed[i] <= elapsed[i-1] {
+			t.Error("elapsed time should grow: ", elapsed)
+		}
+	}
+}
+
+func Test_StartTimer(t *testing.T) {
+	if !StartTime(context.Background()).IsZero() || Elapsed(context.Background()) != 0 {
+		t.Error("no start time should be recorded")
+	}
+
+	c := New(StartTimer()).Run(context.Background(), nil, nil)
+	first := Elapsed(c)
+	time.Sleep(time.Millisecond)
+
+	if StartTime(c).IsZero() {
+		t.Error("start time not recorded")
+	}
+	if Elapsed(c) <= first {
+		t.Error("elapsed time should grow")
+	}
+}
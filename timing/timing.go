@@ -0,0 +1,144 @@
+package timing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// Total is the name of the metric measuring the time spent on the request up to
+// the point the response header is written
+const Total = "total"
+
+type metric struct {
+	name string
+	dur  time.Duration
+}
+
+// timings collects the durations recorded for a request, which may be recorded
+// from multiple goroutines
+type timings struct {
+	mu      sync.Mutex
+	start   time.Time
+	metrics []metric
+	written bool
+}
+
+// Header returns a middleware that writes the durations recorded with Record as a
+// Server-Timing header, along with the total time spent on the request. The
+// header is written along with the response header, so durations recorded after
+// the handler starts writing the response are not included, and is left out if
+// nothing was recorded.
+//	q := quincy.New(timing.Header())
+func Header() quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		t := &timings{start: quincy.StartTime(c)}
+		if t.start.IsZero() {
+			t.start = time.Now()
+		}
+		c = quincy.WithValue(c, t)
+
+		tw := &timingWriter{Wrapper: quincy.Wrapper{ResponseWriter: w}, t: t}
+		// responses that are never written to still need the header set before the
+		// server writes the default response
+		if !quincy.Defer(c, tw.setHeader) {
+			return c
+		}
+		return quincy.WithWriter(c, tw)
+	}
+}
+
+// Record adds a duration under the name to the Server-Timing header, with the
+// durations recorded under the same name being added together. It has no effect
+// unless Header is in the chain.
+//	start := time.Now()
+//	rows, err := query(c)
+//	timing.Record(c, "db", time.Since(start))
+func Record(c context.Context, name string, dur time.Duration) {
+	t, _ := quincy.Value[*timings](c)
+	if t == nil {
+		return
+	}
+
+	name = token(name)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.metrics {
+		if t.metrics[i].name == name {
+			t.metrics[i].dur += dur
+			return
+		}
+	}
+	t.metrics = append(t.metrics, metric{name, dur})
+}
+
+// returns the header value, or an empty string if it was already returned or
+// nothing was recorded
+func (t *timings) header() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.written {
+		return ""
+	}
+	t.written = true
+	if len(t.metrics) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(t.metrics)+1)
+	for _, m := range t.metrics {
+		parts = append(parts, format(m.name, m.dur))
+	}
+	parts = append(parts, format(Total, time.Since(t.start)))
+	return strings.Join(parts, ", ")
+}
+
+// formats the metric with its duration in milliseconds
+func format(name string, dur time.Duration) string {
+	return fmt.Sprintf("%s;dur=%.1f", name, float64(dur)/float64(time.Millisecond))
+}
+
+// replaces the characters that are not allowed in a metric name
+func token(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r > ' ' && r < 0x7f && !strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// timingWriter sets the Server-Timing header before the response header is
+// written
+type timingWriter struct {
+	quincy.Wrapper
+	t *timings
+}
+
+func (tw *timingWriter) setHeader() {
+	if v := tw.t.header(); v != "" {
+		tw.Header().Set("Server-Timing", v)
+	}
+}
+
+func (tw *timingWriter) WriteHeader(status int) {
+	tw.setHeader()
+	tw.ResponseWriter.WriteHeader(status)
+}
+
+func (tw *timingWriter) Write(b []byte) (int, error) {
+	tw.setHeader()
+	return tw.ResponseWriter.Write(b)
+}
+
+// Flush sends the response written so far, if the underlying writer supports it
+func (tw *timingWriter) Flush() {
+	tw.setHeader()
+	tw.Wrapper.Flush()
+}
+
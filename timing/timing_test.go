@@ -0,0 +1,70 @@
+package timing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/testutil"
+)
+
+func serve(fn quincy.HandlerFunc) *httptest.ResponseRecorder {
+	q := quincy.New(Header(), func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		Record(c, "auth", 2*time.Millisecond)
+		return c
+	})
+	q.ContextFunc = testutil.StubContext
+
+	w := httptest.NewRecorder()
+	q.Then(fn)(w, httptest.NewRequest("GET", "/", nil))
+	return w
+}
+
+func Test_Header(t *testing.T) {
+	w := serve(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		Record(c, "db", 10*time.Millisecond)
+		Record(c, "db", 5*time.Millisecond)
+		Record(c, "cache hit", time.Millisecond)
+		w.Write([]byte("ok"))
+		Record(c, "late", time.Millisecond)
+	})
+
+	h := w.Header().Get("Server-Timing")
+	expected := regexp.MustCompile(`^auth;dur=2\.0, db;dur=15\.0, cache_hit;dur=1\.0, total;dur=\d+\.\d$`)
+	if !expected.MatchString(h) {
+		t.Error("invalid Server-Timing header: ", h)
+	}
+}
+
+func Test_Header_NotWritten(t *testing.T) {
+	w := serve(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		Record(c, "db", time.Millisecond)
+	})
+
+	if h := w.Header().Get("Server-Timing"); !regexp.MustCompile(`^auth;dur=2\.0, db;dur=1\.0, total;dur=`).MatchString(h) {
+		t.Error("invalid Server-Timing header: ", h)
+	}
+}
+
+func Test_Header_NothingRecorded(t *testing.T) {
+	q := quincy.New(Header())
+	q.ContextFunc = testutil.StubContext
+
+	w := httptest.NewRecorder()
+	q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})(w, httptest.NewRequest("GET", "/", nil))
+
+	if h := w.Header().Get("Server-Timing"); h != "" {
+		t.Error("header should not be set: ", h)
+	}
+}
+
+func Test_Record_WithoutHeader(t *testing.T) {
+	// should not panic
+	Record(context.Background(), "db", time.Millisecond)
+}
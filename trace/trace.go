@@ -0,0 +1,212 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	mathrand "math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// Header is the request header carrying the trace context set by App Engine,
+// formatted as TRACE_ID/SPAN_ID;o=OPTIONS
+const Header = "X-Cloud-Trace-Context"
+
+// Exporter sends the spans of a sampled request to a tracing backend
+type Exporter interface {
+	Export(c context.Context, spans []*Span)
+}
+
+type config struct {
+	exporter Exporter
+	fraction float64
+}
+
+// Option configures the trace middleware
+type Option func(*config)
+
+// Export sets the exporter the spans of sampled requests are sent to once the
+// request completes
+func Export(e Exporter) Option {
+	return func(cfg *config) {
+		cfg.exporter = e
+	}
+}
+
+// Sample sets the fraction of requests without an inbound trace that are sampled,
+// which defaults to none. Requests with an inbound trace follow its decision.
+func Sample(fraction float64) Option {
+	return func(cfg *config) {
+		cfg.fraction = fraction
+	}
+}
+
+// Span is a timed operation within a trace
+type Span struct {
+	Name     string
+	TraceID  string
+	SpanID   uint64
+	ParentID uint64
+	Sampled  bool
+	Start    time.Time
+	End      time.Time
+
+	trace *spans
+	once  sync.Once
+}
+
+// spans collects the finished spans of a request
+type spans struct {
+	mu    sync.Mutex
+	ended []*Span
+}
+
+// Finish records the end of the span. It is safe to call on a nil Span, and only
+// the first call has an effect.
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		s.End = time.Now()
+		s.trace.mu.Lock()
+		s.trace.ended = append(s.trace.ended, s)
+		s.trace.mu.Unlock()
+	})
+}
+
+// String formats the span as the value of the trace context header, allowing
+// the trace to be continued by outbound requests
+func (s *Span) String() string {
+	o := 0
+	if s.Sampled {
+		o = 1
+	}
+	return fmt.Sprintf("%s/%d;o=%d", s.TraceID, s.SpanID, o)
+}
+
+// Trace returns a middleware that starts a span covering the request, continuing
+// the trace of the X-Cloud-Trace-Context header or starting a new one, and
+// finishes it once the request completes. Child spans are started with
+// StartSpan.
+//	q := quincy.New(trace.Trace(trace.Export(exporter), trace.Sample(0.1)))
+func Trace(opts ...Option) quincy.Middleware {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		traceID, parentID, sampled, ok := parse(r.Header.Get(Header))
+		if !ok {
+			traceID = newTraceID()
+			sampled = cfg.fraction > 0 && mathrand.Float64() < cfg.fraction
+		}
+
+		t := &spans{}
+		root := &Span{
+			Name:     r.URL.Path,
+			TraceID:  traceID,
+			SpanID:   newSpanID(),
+			ParentID: parentID,
+			Sampled:  sampled,
+			Start:    time.Now(),
+			trace:    t,
+		}
+
+		quincy.Defer(c, func() {
+			root.Finish()
+			if root.Sampled && cfg.exporter != nil {
+				t.mu.Lock()
+				ended := t.ended
+				t.mu.Unlock()
+				cfg.exporter.Export(c, ended)
+			}
+		})
+		return quincy.WithValue(c, root)
+	}
+}
+
+// FromContext returns the current span, or nil if Trace did not run
+func FromContext(c context.Context) *Span {
+	s, _ := quincy.Value[*Span](c)
+	return s
+}
+
+// TraceID returns the ID of the trace the request belongs to, or an empty string
+// if Trace did not run
+func TraceID(c context.Context) string {
+	if s := FromContext(c); s != nil {
+		return s.TraceID
+	}
+	return ""
+}
+
+// StartSpan starts a child of the current span, returning a context holding the
+// child so further spans are nested within it. The span is nil, which Finish
+// allows, if Trace did not run.
+//	c, span := trace.StartSpan(c, "datastore.Get")
+//	defer span.Finish()
+func StartSpan(c context.Context, name string) (context.Context, *Span) {
+	parent := FromContext(c)
+	if parent == nil {
+		return c, nil
+	}
+
+	s := &Span{
+		Name:     name,
+		TraceID:  parent.TraceID,
+		SpanID:   newSpanID(),
+		ParentID: parent.SpanID,
+		Sampled:  parent.Sampled,
+		Start:    time.Now(),
+		trace:    parent.trace,
+	}
+	return quincy.WithValue(c, s), s
+}
+
+// parses the trace context header, with a missing option being taken as not
+// sampled
+func parse(header string) (traceID string, spanID uint64, sampled bool, ok bool) {
+	ids, options, _ := strings.Cut(header, ";")
+	traceID, span, _ := strings.Cut(ids, "/")
+	if len(traceID) != 32 {
+		return "", 0, false, false
+	}
+	if _, err := hex.DecodeString(traceID); err != nil {
+		return "", 0, false, false
+	}
+	if span != "" {
+		var err error
+		if spanID, err = strconv.ParseUint(span, 10, 64); err != nil {
+			return "", 0, false, false
+		}
+	}
+	return strings.ToLower(traceID), spanID, options == "o=1", true
+}
+
+// returns a random 128 bit trace ID in hex
+func newTraceID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// returns a random non-zero span ID
+func newSpanID() uint64 {
+	b := make([]byte, 8)
+	for {
+		rand.Read(b)
+		if id := binary.BigEndian.Uint64(b); id != 0 {
+			return id
+		}
+	}
+}
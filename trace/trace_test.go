@@ -0,0 +1,95 @@
+package trace
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chrisolsen/quincy"
+	"github.com/chrisolsen/quincy/testutil"
+)
+
+type exporter struct {
+	spans []*Span
+}
+
+func (e *exporter) Export(c context.Context, spans []*Span) {
+	e.spans = append(e.spans, spans...)
+}
+
+func serve(r *http.Request, fn quincy.HandlerFunc, opts ...Option) {
+	q := quincy.New(Trace(opts...))
+	q.ContextFunc = testutil.StubContext
+	q.Then(fn)(httptest.NewRecorder(), r)
+}
+
+func Test_Trace_Inbound(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(Header, "105445aa7843bc8bf206b12000100000/123;o=1")
+
+	var span *Span
+	serve(r, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		span = FromContext(c)
+		if TraceID(c) != "105445aa7843bc8bf206b12000100000" {
+			t.Error("invalid trace ID: ", TraceID(c))
+		}
+	})
+
+	if span.ParentID != 123 || !span.Sampled {
+		t.Errorf("invalid span: %+v", span)
+	}
+	if span.End.IsZero() {
+		t.Error("span not finished")
+	}
+}
+
+func Test_Trace_New(t *testing.T) {
+	for _, header := range []string{"", "invalid/1;o=1"} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set(Header, header)
+
+		serve(r, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+			span := FromContext(c)
+			if len(span.TraceID) != 32 || span.ParentID != 0 || span.Sampled {
+				t.Errorf("invalid span for %q: %+v", header, span)
+			}
+		})
+	}
+}
+
+func Test_Trace_Export(t *testing.T) {
+	e := &exporter{}
+	r := httptest.NewRequest("GET", "/", nil)
+	serve(r, func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		_, span := StartSpan(c, "child")
+		span.Finish()
+	}, Export(e), Sample(1))
+
+	if len(e.spans) != 2 {
+		t.Fatal("expected 2 spans, got ", len(e.spans))
+	}
+	child, root := e.spans[0], e.spans[1]
+	if child.Name != "child" || child.ParentID != root.SpanID || child.TraceID != root.TraceID {
+		t.Errorf("invalid child span: %+v", child)
+	}
+}
+
+func Test_Trace_NotSampled(t *testing.T) {
+	e := &exporter{}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(Header, "105445aa7843bc8bf206b12000100000/123;o=0")
+	serve(r, func(c context.Context, w http.ResponseWriter, r *http.Request) {}, Export(e), Sample(1))
+
+	if len(e.spans) != 0 {
+		t.Error("unsampled spans should not be exported")
+	}
+}
+
+func Test_StartSpan_WithoutTrace(t *testing.T) {
+	c, span := StartSpan(context.Background(), "child")
+	span.Finish()
+	if c == nil || span != nil {
+		t.Error("expected a nil span")
+	}
+}
@@ -0,0 +1,26 @@
+package uri
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/chrisolsen/quincy"
+)
+
+// MaxLength returns a middleware that aborts the chain with a 414 when the request
+// target, being the path and query string as sent by the client, is longer than n
+// bytes. Percent encoded characters are counted as sent, while the method and
+// protocol of the request line are not counted.
+//	q := quincy.New(uri.MaxLength(2048))
+func MaxLength(n int) quincy.Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		target := r.RequestURI
+		if target == "" {
+			target = r.URL.RequestURI()
+		}
+		if len(target) > n {
+			return quincy.Abort(c, w, http.StatusRequestURITooLong)
+		}
+		return c
+	}
+}
@@ -0,0 +1,29 @@
+package uri
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_MaxLength(t *testing.T) {
+	tests := map[string]bool{
+		"/abc?q=12":     true,
+		"/abc?q=123":    true,
+		"/abc?q=1234":   false,
+		"/%20%20%20%20": false,
+		"/%20%20%20":    true,
+	}
+
+	for target, allowed := range tests {
+		w := httptest.NewRecorder()
+		c := MaxLength(10)(context.Background(), w, httptest.NewRequest("GET", target, nil))
+
+		if (c.Err() == nil) != allowed {
+			t.Errorf("%s: expected allowed to be %v", target, allowed)
+		}
+		if !allowed && w.Code != 414 {
+			t.Errorf("%s: expected 414, got %d", target, w.Code)
+		}
+	}
+}
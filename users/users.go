@@ -0,0 +1,79 @@
+package users
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/chrisolsen/quincy"
+	"google.golang.org/appengine/user"
+)
+
+// the Users API calls behind Require, kept as variables so the tests can sign a
+// user in or out without a dev_appserver
+var (
+	current  = user.Current
+	loginURL = user.LoginURL
+)
+
+type config struct {
+	api   bool
+	admin bool
+}
+
+// Option configures the user middleware
+type Option func(*config)
+
+// API rejects signed out requests with a 401 rather than redirecting them to the
+// login page, for endpoints called by scripts rather than browsers
+func API() Option {
+	return func(cfg *config) {
+		cfg.api = true
+	}
+}
+
+// Admin only allows users who are admins of the app, rejecting other signed in
+// users with a 403
+func Admin() Option {
+	return func(cfg *config) {
+		cfg.admin = true
+	}
+}
+
+// Require returns a middleware that only lets requests from a user signed in with
+// the App Engine Users API through, storing the user on the context to be read
+// with Current. Signed out GET and HEAD requests are redirected to the login
+// page, which returns them to the URL they requested, while other signed out
+// requests are rejected with a 401 since they can't be replayed after logging in.
+//	q := quincy.New(users.Require(users.Admin()))
+func Require(opts ...Option) quincy.Middleware {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		u := current(c)
+		if u == nil {
+			if cfg.api || (r.Method != "GET" && r.Method != "HEAD") {
+				return quincy.Abort(c, w, http.StatusUnauthorized)
+			}
+			url, err := loginURL(c, r.URL.RequestURI())
+			if err != nil {
+				return quincy.Fail(c, http.StatusInternalServerError, err)
+			}
+			http.Redirect(w, r, url, http.StatusFound)
+			return quincy.Stop(c)
+		}
+		if cfg.admin && !u.Admin {
+			return quincy.Abort(c, w, http.StatusForbidden)
+		}
+		return quincy.WithValue(c, u)
+	}
+}
+
+// Current returns the user stored by Require, or nil if Require did not run
+//	fmt.Fprintf(w, "Hello, %s", users.Current(c).Email)
+func Current(c context.Context) *user.User {
+	u, _ := quincy.Value[*user.User](c)
+	return u
+}
@@ -0,0 +1,72 @@
+package users
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/appengine/user"
+)
+
+// fakes the signed in user, which is nil when signed out
+func signIn(u *user.User) {
+	current = func(c context.Context) *user.User {
+		return u
+	}
+	loginURL = func(c context.Context, dest string) (string, error) {
+		return "/_ah/login?continue=" + dest, nil
+	}
+}
+
+func Test_Require_SignedIn(t *testing.T) {
+	u := &user.User{Email: "test@example.com"}
+	signIn(u)
+
+	w := httptest.NewRecorder()
+	c := Require()(context.Background(), w, httptest.NewRequest("GET", "/", nil))
+
+	if c.Err() != nil || Current(c) != u {
+		t.Error("signed in user should be stored on the context")
+	}
+}
+
+func Test_Require_Redirect(t *testing.T) {
+	signIn(nil)
+
+	w := httptest.NewRecorder()
+	Require()(context.Background(), w, httptest.NewRequest("GET", "/account?tab=1", nil))
+
+	if w.Code != 302 {
+		t.Error("expected 302, got ", w.Code)
+	}
+	if loc := w.Header().Get("Location"); loc != "/_ah/login?continue=/account?tab=1" {
+		t.Error("invalid login location: ", loc)
+	}
+}
+
+func Test_Require_API(t *testing.T) {
+	signIn(nil)
+
+	w := httptest.NewRecorder()
+	c := Require(API())(context.Background(), w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != 401 || c.Err() == nil {
+		t.Error("expected 401, got ", w.Code)
+	}
+}
+
+func Test_Require_Admin(t *testing.T) {
+	signIn(&user.User{Email: "test@example.com"})
+	w := httptest.NewRecorder()
+	c := Require(Admin())(context.Background(), w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 403 || c.Err() == nil {
+		t.Error("expected 403 for a non admin, got ", w.Code)
+	}
+
+	signIn(&user.User{Email: "admin@example.com", Admin: true})
+	w = httptest.NewRecorder()
+	c = Require(Admin())(context.Background(), w, httptest.NewRequest("GET", "/", nil))
+	if c.Err() != nil {
+		t.Error("admin should be let through")
+	}
+}
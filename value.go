@@ -0,0 +1,84 @@
+package quincy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// valueKey is unique to each type, so values of different types stored with
+// WithValue never collide
+type valueKey[T any] struct{}
+
+// WithValue returns a context that carries v keyed by its type. Middleware should
+// define their own types for the values they store, rather than using builtin
+// types such as string, to avoid replacing the values of other middleware.
+//	type Token string
+//	c = quincy.WithValue(c, Token("abc"))
+func WithValue[T any](c context.Context, v T) context.Context {
+	return context.WithValue(c, valueKey[T]{}, v)
+}
+
+// Value returns the value of type T carried by the context and whether it was
+// found
+//	token, ok := quincy.Value[Token](c)
+func Value[T any](c context.Context) (T, bool) {
+	v, ok := c.Value(valueKey[T]{}).(T)
+	return v, ok
+}
+
+// store holds the values set with Set, keyed by their type
+type store struct {
+	mu     sync.RWMutex
+	values map[reflect.Type]any
+}
+
+type storeKey struct{}
+
+// Set stores v keyed by its type in a map shared by the whole request, so unlike
+// WithValue only the first call adds a layer to the context. Since the map is
+// shared, a value set by a later middleware is also seen through the contexts of
+// the middleware before it, and setting a type again replaces its value.
+//	c = quincy.Set(c, Token("abc"))
+func Set[T any](c context.Context, v T) context.Context {
+	s, ok := c.Value(storeKey{}).(*store)
+	if !ok {
+		s = &store{values: map[reflect.Type]any{}}
+		c = context.WithValue(c, storeKey{}, s)
+	}
+	s.mu.Lock()
+	s.values[typeOf[T]()] = v
+	s.mu.Unlock()
+	return c
+}
+
+// Get returns the value of type T stored with Set and whether it was found
+//	token, ok := quincy.Get[Token](c)
+func Get[T any](c context.Context) (T, bool) {
+	var v T
+	s, ok := c.Value(storeKey{}).(*store)
+	if !ok {
+		return v, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok = s.values[typeOf[T]()].(T)
+	return v, ok
+}
+
+// MustGet returns the value of type T stored with Set, panicking if there is none.
+// It suits values that an earlier middleware is known to have set.
+//	account := quincy.MustGet[*Account](c)
+func MustGet[T any](c context.Context) T {
+	v, ok := Get[T](c)
+	if !ok {
+		panic(fmt.Sprintf("quincy: no %v value set on the context", typeOf[T]()))
+	}
+	return v
+}
+
+// returns the type of T, including interface types
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
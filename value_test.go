@@ -0,0 +1,87 @@
+package quincy
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+type testToken string
+
+type testAccount struct {
+	ID int
+}
+
+func Test_Value(t *testing.T) {
+	mw1 := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		c = WithValue(c, testToken("foobar"))
+		return WithValue(c, &testAccount{ID: 1})
+	}
+
+	mw2 := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		token, ok := Value[testToken](c)
+		if !ok || token != "foobar" {
+			t.Error("token does not match the expected")
+		}
+
+		account, ok := Value[*testAccount](c)
+		if !ok || account.ID != 1 {
+			t.Error("account does not match the expected")
+		}
+		return c
+	}
+
+	New(mw1, mw2).Run(context.Background(), nil, nil)
+}
+
+func Test_ValueMissing(t *testing.T) {
+	c := WithValue(context.Background(), testToken("foobar"))
+	if _, ok := Value[string](c); ok {
+		t.Error("values should be keyed by type")
+	}
+}
+
+func Test_Set(t *testing.T) {
+	mw1 := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return Set(c, testToken("foobar"))
+	}
+	mw2 := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		next := Set(c, &testAccount{ID: 1})
+		if next != c {
+			t.Error("only the first Set should add to the context")
+		}
+		return Set(next, 42)
+	}
+	mw3 := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		if token, ok := Get[testToken](c); !ok || token != "foobar" {
+			t.Error("token does not match the expected")
+		}
+		if account := MustGet[*testAccount](c); account.ID != 1 {
+			t.Error("account does not match the expected")
+		}
+		if n, ok := Get[int](c); !ok || n != 42 {
+			t.Error("int does not match the expected")
+		}
+		return c
+	}
+
+	New(mw1, mw2, mw3).Run(context.Background(), nil, nil)
+}
+
+func Test_GetMissing(t *testing.T) {
+	if _, ok := Get[testToken](context.Background()); ok {
+		t.Error("nothing should be found without a store")
+	}
+
+	c := Set(context.Background(), testToken("foobar"))
+	if _, ok := Get[string](c); ok {
+		t.Error("values should be keyed by type")
+	}
+
+	defer func() {
+		if v := recover(); v != "quincy: no string value set on the context" {
+			t.Error("invalid panic: ", v)
+		}
+	}()
+	MustGet[string](c)
+}
@@ -0,0 +1,25 @@
+package quincy
+
+import (
+	"context"
+	"net/http"
+)
+
+// When returns a middleware that only calls mw for requests in which pred
+// returns true, passing the context through untouched for all others
+//	q.Add(quincy.When(isGET, gzip))
+func When(pred func(*http.Request) bool, mw Middleware) Middleware {
+	return func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		if !pred(r) {
+			return c
+		}
+		return mw(c, w, r)
+	}
+}
+
+// Unless returns a middleware that only calls mw for requests in which pred
+// returns false
+//	q.Add(quincy.Unless(isHealthCheck, auth))
+func Unless(pred func(*http.Request) bool, mw Middleware) Middleware {
+	return When(func(r *http.Request) bool { return !pred(r) }, mw)
+}
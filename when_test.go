@@ -0,0 +1,80 @@
+package quincy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func isGET(r *http.Request) bool {
+	return r.Method == "GET"
+}
+
+func Test_WhenMatching(t *testing.T) {
+	var calls []string
+	q := New(When(isGET, record("a", &calls)))
+	q.Run(context.Background(), nil, httptest.NewRequest("GET", "/", nil))
+
+	if len(calls) != 1 {
+		t.Error("middleware should run when the predicate is true")
+	}
+}
+
+func Test_WhenNotMatching(t *testing.T) {
+	mw := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		t.Error("middleware should not run when the predicate is false")
+		return context.WithValue(c, "key", "foobar")
+	}
+
+	c := context.Background()
+	if When(isGET, mw)(c, nil, httptest.NewRequest("POST", "/", nil)) != c {
+		t.Error("context should be passed through untouched")
+	}
+}
+
+func Test_Unless(t *testing.T) {
+	isHealth := func(r *http.Request) bool {
+		return r.URL.Path == "/health"
+	}
+
+	var calls []string
+	q := New(Unless(isHealth, record("auth", &calls)))
+
+	q.Run(context.Background(), nil, httptest.NewRequest("GET", "/health", nil))
+	if len(calls) != 0 {
+		t.Error("middleware should not run for an excluded path")
+	}
+
+	q.Run(context.Background(), nil, httptest.NewRequest("GET", "/accounts", nil))
+	if len(calls) != 1 {
+		t.Error("middleware should run for a non-excluded path")
+	}
+}
+
+func Test_WhenTerminal(t *testing.T) {
+	var calls []string
+	mock := func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "mock")
+		w.WriteHeader(http.StatusAccepted)
+	}
+
+	q := New(record("a", &calls))
+	q.ContextFunc = background
+	q.Add(When(isGET, Terminal(mock)), record("b", &calls))
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	})
+
+	w := httptest.NewRecorder()
+	fn(w, httptest.NewRequest("GET", "/", nil))
+	fn(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil))
+
+	if w.Code != http.StatusAccepted {
+		t.Error("Invalid response status: ", w.Code)
+	}
+	if strings.Join(calls, ",") != "a,mock,a,b,handler" {
+		t.Error("invalid calls: ", calls)
+	}
+}
@@ -0,0 +1,60 @@
+package quincy
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// Wrapper is embedded by ResponseWriters that wrap another, such as one replacing
+// the writer with WithWriter. It forwards the optional interfaces of the wrapped
+// writer, so flushing, hijacking, close notification and server push keep working
+// through the wrapper, and Unwrap lets http.ResponseController reach the writer
+// beneath it. A wrapper only needs to override the methods it changes.
+//	type gzipWriter struct {
+//		quincy.Wrapper
+//		gz *gzip.Writer
+//	}
+//	gw := &gzipWriter{Wrapper: quincy.Wrapper{ResponseWriter: w}}
+type Wrapper struct {
+	http.ResponseWriter
+}
+
+// Unwrap returns the wrapped ResponseWriter
+func (w Wrapper) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush sends any buffered data to the client if the wrapped writer supports it
+func (w Wrapper) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack takes over the connection if the wrapped writer supports it, returning
+// http.ErrNotSupported otherwise
+func (w Wrapper) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// CloseNotify returns the channel of the wrapped writer that receives a value when
+// the client goes away, or a channel that never receives if it isn't supported
+func (w Wrapper) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// Push initiates an HTTP/2 server push if the wrapped writer supports it,
+// returning http.ErrNotSupported otherwise
+func (w Wrapper) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
@@ -0,0 +1,72 @@
+package quincy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// deadlineRecorder is a ResponseRecorder that records the write deadline set
+// through http.ResponseController
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+	deadline time.Time
+}
+
+func (d *deadlineRecorder) SetWriteDeadline(t time.Time) error {
+	d.deadline = t
+	return nil
+}
+
+// headerWriter wraps a writer to set a header before the response is written
+type headerWriter struct {
+	Wrapper
+}
+
+func (h headerWriter) WriteHeader(status int) {
+	h.Header().Set("X-Wrapped", "true")
+	h.Wrapper.WriteHeader(status)
+}
+
+func Test_WrapperResponseController(t *testing.T) {
+	deadline := time.Now().Add(time.Minute)
+	wrap := func(c context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+		return WithWriter(c, headerWriter{Wrapper{ResponseWriter: w}})
+	}
+
+	q := New(wrap)
+	q.ContextFunc = background
+	fn := q.Then(func(c context.Context, w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil {
+			t.Error("unexpected error: ", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	w := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+	fn(w, httptest.NewRequest("GET", "/", nil))
+
+	if !w.deadline.Equal(deadline) {
+		t.Error("the write deadline should reach the underlying writer: ", w.deadline)
+	}
+	if w.Code != http.StatusCreated || w.Header().Get("X-Wrapped") != "true" {
+		t.Error("invalid response: ", w.Code, w.Header())
+	}
+}
+
+func Test_WrapperNotSupported(t *testing.T) {
+	w := Wrapper{ResponseWriter: httptest.NewRecorder()}
+	if _, _, err := w.Hijack(); err != http.ErrNotSupported {
+		t.Error("invalid hijack error: ", err)
+	}
+	if err := w.Push("/app.css", nil); err != http.ErrNotSupported {
+		t.Error("invalid push error: ", err)
+	}
+	select {
+	case <-w.CloseNotify():
+		t.Error("close notify should never receive")
+	default:
+	}
+}